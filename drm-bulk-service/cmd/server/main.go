@@ -9,8 +9,13 @@ import (
   "drm-bulk-service/internal/api"
   "drm-bulk-service/internal/config"
   "drm-bulk-service/internal/db"
+  "drm-bulk-service/internal/export"
   grpcclient "drm-bulk-service/internal/grpc"
+  "drm-bulk-service/internal/ingest"
+  "drm-bulk-service/internal/lifecycle"
+  "drm-bulk-service/internal/report"
   "drm-bulk-service/internal/repository"
+  "drm-bulk-service/internal/worker"
 )
 
 func main() {
@@ -37,6 +42,19 @@ func main() {
   reportRepo := repository.NewBulkReportRepository(mongoConn.DB)
   schemaRepo := repository.NewSchemaRepository(mongoConn.DB)
 
+  // Reports read items in file order
+  if err := bulkItemRepo.EnsureIndexes(context.Background()); err != nil {
+    log.Fatal("Failed to create bulk item indexes:", err)
+  }
+  // One report document per request and version
+  if err := reportRepo.EnsureIndexes(context.Background()); err != nil {
+    log.Fatal("Failed to create bulk report indexes:", err)
+  }
+  // Allocation claims the lowest Available value of a type, numeric or not
+  if err := repository.NewInventoryLogicalRepository(mongoConn.DB).EnsureAllocationIndexes(context.Background()); err != nil {
+    log.Fatal("Failed to create allocation indexes:", err)
+  }
+
   // Create Inventory gRPC client (Logical + Physical)
   // replace "localhost:50051" with real address in non-local env
   address := os.Getenv("INVENTORY_GRPC_ADDRESS")
@@ -49,6 +67,67 @@ func main() {
   }
   log.Println("Inventory gRPC connected")
 
+  // Resource lifecycle rules (STATUS_TRANSITIONS overrides the defaults)
+  sm, err := lifecycle.Parse(cfg.StatusTransitions)
+  if err != nil {
+    log.Fatalf("invalid STATUS_TRANSITIONS: %v", err)
+  }
+
+  // MSISDN classification rules (MOBILE_CLASS_RULES overrides the defaults)
+  classifier, err := ingest.ParseClassRules(cfg.MobileClassRules)
+  if err != nil {
+    log.Fatalf("invalid MOBILE_CLASS_RULES: %v", err)
+  }
+
+  // Identifier normalization (E.164 MSISDNs, Luhn / MCC-MNC checks)
+  normalizer, err := ingest.NewNormalizer(cfg.MSISDNCountryCode, cfg.MSISDNNationalLength, cfg.IMSIHomePLMNs)
+  if err != nil {
+    log.Fatalf("invalid MSISDN_COUNTRY_CODE / MSISDN_NATIONAL_LENGTH / IMSI_HOME_PLMNS: %v", err)
+  }
+  if cfg.MSISDNCountryCode == "" {
+    log.Println("WARNING: MSISDN_COUNTRY_CODE is not set, national MSISDNs (e.g. 0912...) are stored without a country code")
+  }
+
+  // Release expired reservations in the background
+  sweepInterval, err := time.ParseDuration(cfg.ReservationSweepInterval)
+  if err != nil {
+    log.Fatalf("invalid RESERVATION_SWEEP_INTERVAL: %v", err)
+  }
+  if sweepInterval > 0 {
+    sweeper := worker.NewReservationSweeper(
+      bulkItemRepo,
+      bulkReqRepo,
+      report.NewService(bulkItemRepo, reportRepo, mongoConn.DB),
+      sm,
+      repository.NewInventoryLogicalRepository(mongoConn.DB),
+      repository.NewInventoryPhysicalRepository(mongoConn.DB),
+    )
+    go sweeper.Run(context.Background(), sweepInterval)
+  }
+
+  // Purge expired items, reports and exports in the background
+  retention, err := worker.ParseRetentionPolicy(cfg.ItemRetentionDays, cfg.ReportRetentionDays, cfg.ExportRetentionDays)
+  if err != nil {
+    log.Fatalf("invalid ITEM_RETENTION_DAYS / REPORT_RETENTION_DAYS / EXPORT_RETENTION_DAYS: %v", err)
+  }
+  retentionInterval, err := time.ParseDuration(cfg.RetentionInterval)
+  if err != nil {
+    log.Fatalf("invalid RETENTION_INTERVAL: %v", err)
+  }
+  if retentionInterval > 0 {
+    janitor := worker.NewRetentionJanitor(
+      retention,
+      bulkReqRepo,
+      bulkItemRepo,
+      repository.NewBulkItemSnapshotRepository(mongoConn.DB),
+      reportRepo,
+      report.NewService(bulkItemRepo, reportRepo, mongoConn.DB),
+      repository.NewExportJobRepository(mongoConn.DB),
+      export.NewService(mongoConn.DB),
+    )
+    go janitor.Run(context.Background(), retentionInterval)
+  }
+
   // Create HTTP API server with all dependencies injected
   server := api.NewServer(
     cfg,
@@ -58,6 +137,9 @@ func main() {
     invClient,
     mongoConn.DB,
     schemaRepo,
+    sm,
+    classifier,
+    normalizer,
   )
 
   // Start HTTP server
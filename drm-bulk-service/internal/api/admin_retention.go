@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"drm-bulk-service/internal/export"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/admin/retention/purge
+Runs the retention purge now
+
+Deletes BulkItems older than ITEM_RETENTION_DAYS, report versions older
+than REPORT_RETENTION_DAYS and export jobs finished more than
+EXPORT_RETENTION_DAYS ago (with their GridFS files), exactly as the
+background janitor does, and returns what was removed.
+===========================
+*/
+func (s *Server) handleRetentionPurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	policy, err := worker.ParseRetentionPolicy(s.cfg.ItemRetentionDays, s.cfg.ReportRetentionDays, s.cfg.ExportRetentionDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	janitor := worker.NewRetentionJanitor(
+		policy,
+		s.bulkReqRepo,
+		s.bulkItemRepo,
+		repository.NewBulkItemSnapshotRepository(s.db),
+		s.reportRepo,
+		report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+		repository.NewExportJobRepository(s.db),
+		export.NewService(s.db),
+	)
+
+	result, err := janitor.PurgeOnce(r.Context())
+	if err != nil {
+		log.Printf("retention: purge failed: %v", err)
+		http.Error(w, "retention purge failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/allocate
+Number pool ALLOCATION entrypoint
+
+Picks "count" Available logicalresources matching the criteria and
+moves them to targetStatus. Each resource is claimed atomically, so
+concurrent allocations never receive the same number. The allocated
+list is the request report (GET /{requestId}/report).
+
+JSON body:
+
+	{
+	  "type": "MSISDN",
+	  "category": "Gold",            // category or MobileClass characteristic
+	  "valuePattern": "8007xxxxx",   // x = any digit
+	  "count": 500,
+	  "targetStatus": "Reserved",    // default Reserved
+	  "partyName": "ACME Corp",      // optional relatedParty
+	  "partyRole": "Customer",
+	  "allowPartial": false,         // default: all or nothing
+	  "userName": "...", "userRole": "...", "userType": "...", "userBaseType": "..."
+	}
+
+===========================
+*/
+type allocateRequest struct {
+	model.AllocationCriteria
+
+	Count        int    `json:"count"`
+	TargetStatus string `json:"targetStatus"`
+	PartyName    string `json:"partyName"`
+	PartyRole    string `json:"partyRole"`
+	AllowPartial bool   `json:"allowPartial"`
+
+	UserName     string `json:"userName"`
+	UserRole     string `json:"userRole"`
+	UserType     string `json:"userType"`
+	UserBaseType string `json:"userBaseType"`
+}
+
+func (s *Server) handleBulkAllocate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body allocateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if body.Type == "" {
+		http.Error(w, "type is required", http.StatusBadRequest)
+		return
+	}
+	if body.Count <= 0 || body.Count > maxRangeSize {
+		http.Error(w, fmt.Sprintf("count must be between 1 and %d", maxRangeSize), http.StatusBadRequest)
+		return
+	}
+	if body.ValuePattern != "" {
+		if _, err := repository.ValuePatternRegex(body.ValuePattern); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if body.TargetStatus == "" {
+		body.TargetStatus = lifecycle.StatusReserved
+	}
+	if err := s.lifecycle.Validate(lifecycle.StatusAvailable, body.TargetStatus); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var party *model.RelatedParty
+	if body.PartyName != "" {
+		party = &model.RelatedParty{Name: body.PartyName, Role: body.PartyRole, Type: "RelatedParty", BaseType: "RelatedParty"}
+		if party.Role == "" {
+			party.Role = "Customer"
+		}
+	}
+
+	req := model.BulkRequest{
+		Operation:    "allocate",
+		Type:         body.Type,
+		BaseType:     "LogicalResource",
+		FileName:     fmt.Sprintf("allocate %d %s", body.Count, body.Type),
+		UserName:     body.UserName,
+		UserRole:     body.UserRole,
+		UserType:     body.UserType,
+		UserBaseType: body.UserBaseType,
+	}
+	if err := s.bulkReqRepo.Insert(r.Context(), &req); err != nil {
+		http.Error(w, "failed to create request", http.StatusInternalServerError)
+		return
+	}
+
+	// numeric identifiers are claimed in numeric order (9 before 10000)
+	body.AllocationCriteria.NumericOrder = s.numericValueTypes().Ordered(body.Type)
+
+	spec := worker.AllocationSpec{
+		Criteria:     body.AllocationCriteria,
+		Count:        body.Count,
+		ToStatus:     body.TargetStatus,
+		Party:        party,
+		AllowPartial: body.AllowPartial,
+	}
+
+	go func(req model.BulkRequest) {
+		processor := worker.NewAllocationProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+		)
+		processor.Process(context.Background(), req, spec)
+	}(req)
+
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"requestId": req.ID.Hex(),
+		"status":    "pending",
+	})
+}
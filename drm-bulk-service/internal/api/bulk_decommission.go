@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/retire
+POST /v1/drm-bulk/resources/delete
+Bulk DECOMMISSION entrypoints
+
+Resources that are InUse, or referenced from another resource's
+resourceRelationship / bundledResources, are refused per item.
+Retire sets resourceStatus=Retired, endOperatingDate=now and
+resourceRecycleDate=now+recycleAfterDays.
+
+Input is either a CSV file (value,type per row):
+
+	value,type
+	800700000,MSISDN
+
+or a numeric range instead of the file:
+
+	valueFrom = 800700000
+	valueTo   = 800700999
+
+Form-data:
+
+	type              = MSISDN (default type when the row has none)
+	baseType          = LogicalResource | PhysicalResource
+	recycleAfterDays  = 90 (retire only, default RECYCLE_AFTER_DAYS)
+	skipLines         = 1
+	user*             = user info
+
+===========================
+*/
+func (s *Server) handleBulkRetire(w http.ResponseWriter, r *http.Request) {
+	s.handleBulkDecommission(w, r, "retire")
+}
+
+func (s *Server) handleBulkDelete(w http.ResponseWriter, r *http.Request) {
+	s.handleBulkDecommission(w, r, "delete")
+}
+
+func (s *Server) handleBulkDecommission(w http.ResponseWriter, r *http.Request, operation string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, csvHeader, rows, ok := readValueRows(w, r)
+	if !ok {
+		return
+	}
+
+	recycleDays, err := strconv.Atoi(s.cfg.RecycleAfterDays)
+	if err != nil {
+		recycleDays = 90
+	}
+	if v := r.FormValue("recycleAfterDays"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			http.Error(w, "invalid recycleAfterDays", http.StatusBadRequest)
+			return
+		}
+		recycleDays = days
+	}
+
+	req := newBulkRequest(r, operation, source)
+	setInputHeader(&req, csvHeader, "value", "type")
+
+	toStatus := ""
+	if operation == "retire" {
+		toStatus = lifecycle.StatusRetired
+	}
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		value := row.field(0)
+		if value == "" {
+			continue
+		}
+		itemType := row.field(1)
+		if itemType == "" {
+			itemType = req.Type
+		}
+
+		items = append(items, model.BulkItem{
+			Value:    value,
+			Type:     itemType,
+			BaseType: req.BaseType,
+			Status:   "pending",
+			ToStatus: toStatus,
+
+			LineNumber: row.Line,
+			RawRow:     row.Fields,
+		})
+	}
+
+	s.lookupPipeline().Run(items)
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		processor := worker.NewDecommissionProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			s.lifecycle,
+			repository.NewInventoryReferenceRepository(s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+			time.Duration(recycleDays)*24*time.Hour,
+		)
+		processor.Process(ctx, req, items)
+	})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"drm-bulk-service/internal/export"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/exports
+Asynchronous EXPORT into GridFS
+
+Takes the filters of GET /v1/drm-bulk/resources/export (query string or
+form fields: baseType, type, resourceStatus, valueFrom, valueTo, fields,
+limit and the category / businessType / date / relatedParty / char filters)
+but has no default limit. A JSON body (Content-Type: application/json) is
+read as a filter tree (model.ExportFilter) and ANDed with the params. The export runs in the background and
+survives the client disconnecting; poll the status endpoint and download
+the file once the job is completed.
+
+	userName, userRole = user info (optional)
+
+===========================
+*/
+func (s *Server) handleExportCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body *model.ExportFilter
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		body = &model.ExportFilter{}
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			http.Error(w, "invalid filter body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid query", http.StatusBadRequest)
+		return
+	}
+	query, err := export.ParseQuery(r.Form, 0, s.numericValueTypes())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body != nil {
+		if query.Filter == nil {
+			query.Filter = body
+		} else {
+			query.Filter.And = append(query.Filter.And, *body)
+		}
+	}
+
+	job := model.ExportJob{
+		Query:    query,
+		UserName: r.FormValue("userName"),
+		UserRole: r.FormValue("userRole"),
+	}
+
+	jobRepo := repository.NewExportJobRepository(s.db)
+	if err := jobRepo.Insert(r.Context(), &job); err != nil {
+		log.Printf("export: failed to create job: %v", err)
+		http.Error(w, "failed to create export", http.StatusInternalServerError)
+		return
+	}
+
+	// Start background export; it does not depend on this request's context
+	go func() {
+		processor := worker.NewExportProcessor(jobRepo, export.NewService(s.db))
+		processor.Process(context.Background(), job)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"exportId": job.ID.Hex(),
+		"status":   "pending",
+	})
+}
+
+/*
+===========================
+GET /v1/drm-bulk/exports/{id}
+GET /v1/drm-bulk/exports/{id}/download
+===========================
+*/
+func (s *Server) handleExportGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/drm-bulk/exports/")
+	download := strings.HasSuffix(id, "/download")
+	id = strings.TrimSuffix(id, "/download")
+
+	jobID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	job, err := repository.NewExportJobRepository(s.db).GetByID(ctx, jobID.Hex())
+	if err != nil {
+		http.Error(w, "Export not found", http.StatusNotFound)
+		return
+	}
+
+	if !download {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	if job.Status != "completed" || job.FileID == nil {
+		http.Error(w, "Export not ready", http.StatusNotFound)
+		return
+	}
+
+	bucket, err := gridfs.NewBucket(s.db)
+	if err != nil {
+		http.Error(w, "Storage error", http.StatusInternalServerError)
+		return
+	}
+
+	stream, err := bucket.OpenDownloadStream(*job.FileID)
+	if err != nil {
+		http.Error(w, "Failed to read export", http.StatusInternalServerError)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set(
+		"Content-Disposition",
+		"attachment; filename="+job.FileName,
+	)
+
+	// Stream GridFS file directly to HTTP response
+	if _, err := io.Copy(w, stream); err != nil {
+		log.Println("Failed to stream export:", err)
+	}
+}
+
+// numericValueTypes are the resource types whose values compare as numbers (NUMERIC_VALUE_TYPES)
+func (s *Server) numericValueTypes() model.NumericValueTypes {
+	return model.ParseNumericValueTypes(s.cfg.NumericValueTypes)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/link
+Bulk RELATIONSHIP entrypoint
+
+Attaches a resourceRelationship (reliesOn, bundle, dependency,
+starterPack, capacity, pool) from the source resource to the target
+resource. A "bundle" also lists the target in bundledResources and
+sets isBundle. Rows whose source or target cannot be found are
+reported as unresolved references.
+
+CSV format (columns after targetValue are optional per row):
+
+	sourceValue,targetValue,relationshipType,startDateTime,endDateTime,sourceType,targetType
+	800700000,432110000000001,reliesOn,2024-01-01,2025-01-01,MSISDN,IMSI
+
+Form-data:
+
+	type              = MSISDN (default source type)
+	baseType          = LogicalResource | PhysicalResource (source side)
+	targetType        = IMSI (default target type)
+	targetBaseType    = LogicalResource | PhysicalResource (default: baseType)
+	relationshipType  = reliesOn (default when the row has none)
+	skipLines         = 1
+	user*             = user info
+
+===========================
+*/
+func (s *Server) handleBulkLinkUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, header, ok := parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	skip, _ := strconv.Atoi(r.FormValue("skipLines"))
+
+	req := newBulkRequest(r, "link", header.Filename)
+
+	targetBaseType := r.FormValue("targetBaseType")
+	if targetBaseType == "" {
+		targetBaseType = req.BaseType
+	}
+
+	csvHeader, rows, err := readCSVRows(file, skip)
+	if err != nil {
+		http.Error(w, "failed to read CSV", http.StatusBadRequest)
+		return
+	}
+	setInputHeader(&req, csvHeader,
+		"sourceValue", "targetValue", "relationshipType", "startDateTime", "endDateTime", "sourceType", "targetType")
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		sourceValue, targetValue := row.field(0), row.field(1)
+		if sourceValue == "" || targetValue == "" {
+			continue
+		}
+
+		spec := &model.LinkSpec{
+			RelationshipType: row.field(2),
+			TargetValue:      targetValue,
+			TargetType:       row.field(6),
+			TargetBaseType:   targetBaseType,
+			StartDateTime:    row.field(3),
+			EndDateTime:      row.field(4),
+		}
+		if spec.RelationshipType == "" {
+			spec.RelationshipType = r.FormValue("relationshipType")
+		}
+		if spec.TargetType == "" {
+			spec.TargetType = r.FormValue("targetType")
+		}
+
+		sourceType := row.field(5)
+		if sourceType == "" {
+			sourceType = req.Type
+		}
+
+		items = append(items, model.BulkItem{
+			Value:    sourceValue,
+			Type:     sourceType,
+			BaseType: req.BaseType,
+			Status:   "pending",
+			Link:     spec,
+
+			LineNumber: row.Line,
+			RawRow:     row.Fields,
+		})
+	}
+
+	s.lookupPipeline().Run(items)
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		processor := worker.NewLinkProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+		)
+		processor.Process(ctx, req, items)
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"drm-bulk-service/internal/report"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+===========================
+GET /v1/drm-bulk/resources/{id}/report?partial=true
+Streams the current state of the request's items, pending rows included
+
+Available while the job is still running so operators can start fixing
+early failures. The rows are read from bulk_items on the fly and not
+stored; format and only=failures apply as for the final report.
+===========================
+*/
+func (s *Server) handlePartialReport(w http.ResponseWriter, r *http.Request, reqID primitive.ObjectID, format string) {
+	failuresOnly := r.URL.Query().Get("only") == "failures"
+	if failuresOnly && format != report.FormatCSV {
+		http.Error(w, "The failures report is only available as csv", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	req, err := s.bulkReqRepo.GetByID(ctx, reqID.Hex())
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if req.ItemsPurgedAt != nil {
+		http.Error(w, report.ErrItemsPurged.Error(), http.StatusGone)
+		return
+	}
+
+	fileName := fmt.Sprintf("bulk_report_%s_partial.%s", reqID.Hex(), format)
+	if failuresOnly {
+		fileName = fmt.Sprintf("bulk_failures_%s_partial.csv", reqID.Hex())
+	}
+
+	w.Header().Set("Content-Type", report.ContentType(format))
+	w.Header().Set(
+		"Content-Disposition",
+		"attachment; filename="+fileName,
+	)
+	w.Header().Set("X-Bulk-Request-Status", req.Status)
+
+	// Headers are sent with the first row; a failure after that can only be logged
+	svc := report.NewService(s.bulkItemRepo, s.reportRepo, s.db)
+	if err := svc.WritePartial(ctx, w, *req, format, failuresOnly); err != nil {
+		log.Printf("Failed to stream partial report %s: %v", reqID.Hex(), err)
+	}
+}
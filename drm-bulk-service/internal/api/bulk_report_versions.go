@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/{id}/report/regenerate
+Writes a new report version from the current item states
+
+Use after retries or manual fixes; earlier versions stay downloadable
+with ?version= on the report endpoint.
+===========================
+*/
+func (s *Server) handleReportRegenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(
+		strings.TrimPrefix(r.URL.Path, "/v1/drm-bulk/resources/"),
+		"/report/regenerate",
+	)
+	reqID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	req, err := s.bulkReqRepo.GetByID(ctx, reqID.Hex())
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if req.Status != "completed" && req.Status != "failed" {
+		http.Error(w, "request is still being processed", http.StatusConflict)
+		return
+	}
+
+	reportDoc, err := report.NewService(s.bulkItemRepo, s.reportRepo, s.db).Regenerate(ctx, *req)
+	if err != nil {
+		if errors.Is(err, report.ErrItemsPurged) {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "report is already being regenerated", http.StatusConflict)
+			return
+		}
+		log.Printf("regenerate: failed for %s: %v", reqID.Hex(), err)
+		http.Error(w, "Failed to regenerate report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(reportDoc)
+}
+
+/*
+===========================
+GET /v1/drm-bulk/resources/{id}/reports
+Lists all report versions with their counts and timestamps
+===========================
+*/
+func (s *Server) handleReportList(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(
+		strings.TrimPrefix(r.URL.Path, "/v1/drm-bulk/resources/"),
+		"/reports",
+	)
+	reqID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	reports, err := s.reportRepo.FindAllByRequestID(r.Context(), reqID.Hex())
+	if err != nil {
+		log.Printf("reports: failed to list %s: %v", reqID.Hex(), err)
+		http.Error(w, "Failed to list reports", http.StatusInternalServerError)
+		return
+	}
+	if reports == nil {
+		reports = []model.BulkReport{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"requestId": reqID.Hex(),
+		"reports":   reports,
+	})
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/reserve
+Bulk RESERVE entrypoint
+
+Moves Available resources to Reserved for a customer. The customer is
+added to relatedParty and the reservation expiry is stored on the
+resource; the reservation sweeper releases expired reservations back
+to Available (recorded as a system BulkRequest, operation "release").
+
+Input is either a CSV file (value,type per row) or a numeric range
+(valueFrom / valueTo), as for retire / delete.
+
+Form-data:
+
+	type         = MSISDN (default type when the row has none)
+	baseType     = LogicalResource | PhysicalResource
+	partyName    = ACME Corp (required)
+	partyRole    = Customer (default)
+	expiresAt    = 2026-12-31T00:00:00Z (RFC3339 or 2006-01-02), or
+	reserveDays  = 30 (default RESERVATION_DAYS)
+	skipLines    = 1
+	user*        = user info
+
+===========================
+*/
+func (s *Server) handleBulkReserve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, csvHeader, rows, ok := readValueRows(w, r)
+	if !ok {
+		return
+	}
+
+	party := model.RelatedParty{
+		Name:     r.FormValue("partyName"),
+		Role:     r.FormValue("partyRole"),
+		Type:     "RelatedParty",
+		BaseType: "RelatedParty",
+	}
+	if party.Name == "" {
+		http.Error(w, "partyName is required", http.StatusBadRequest)
+		return
+	}
+	if party.Role == "" {
+		party.Role = "Customer"
+	}
+
+	expiresAt, err := s.reservationExpiry(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := newBulkRequest(r, "reserve", source)
+	setInputHeader(&req, csvHeader, "value", "type")
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		value := row.field(0)
+		if value == "" {
+			continue
+		}
+		itemType := row.field(1)
+		if itemType == "" {
+			itemType = req.Type
+		}
+
+		items = append(items, model.BulkItem{
+			Value:    value,
+			Type:     itemType,
+			BaseType: req.BaseType,
+			Status:   "pending",
+			ToStatus: lifecycle.StatusReserved,
+
+			LineNumber: row.Line,
+			RawRow:     row.Fields,
+		})
+	}
+
+	s.lookupPipeline().Run(items)
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		processor := worker.NewReservationProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			s.lifecycle,
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+			party,
+			expiresAt,
+		)
+		processor.Process(ctx, req, items)
+	})
+}
+
+// reservationExpiry reads expiresAt, or reserveDays (default RESERVATION_DAYS) from now
+func (s *Server) reservationExpiry(r *http.Request) (time.Time, error) {
+	if v := r.FormValue("expiresAt"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", v)
+		}
+		if err != nil {
+			return time.Time{}, errors.New("invalid expiresAt")
+		}
+		if !t.After(time.Now()) {
+			return time.Time{}, errors.New("expiresAt must be in the future")
+		}
+		return t, nil
+	}
+
+	days, err := strconv.Atoi(s.cfg.ReservationDays)
+	if err != nil {
+		days = 30
+	}
+	if v := r.FormValue("reserveDays"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			return time.Time{}, errors.New("invalid reserveDays")
+		}
+	}
+	return time.Now().Add(time.Duration(days) * 24 * time.Hour), nil
+}
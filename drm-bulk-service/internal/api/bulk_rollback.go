@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/{id}/rollback
+Undo a completed bulk UPDATE
+
+Restores the before-images captured in bulk_item_snapshots as a new
+BulkRequest (operation=rollback, parentRequestId={id}). Items whose
+inventory document was modified again after the original job are
+reported as "skipped".
+
+Form-data / query (optional):
+
+	user* = user info
+
+===========================
+*/
+func (s *Server) handleBulkRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(
+		strings.TrimPrefix(r.URL.Path, "/v1/drm-bulk/resources/"),
+		"/rollback",
+	)
+	parentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	parent, err := s.bulkReqRepo.GetByID(ctx, parentID.Hex())
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if parent.Operation != "update" {
+		http.Error(w, "only bulk update requests can be rolled back", http.StatusConflict)
+		return
+	}
+	if parent.Status != "completed" {
+		http.Error(w, "request is not completed yet", http.StatusConflict)
+		return
+	}
+
+	snapshotRepo := repository.NewBulkItemSnapshotRepository(s.db)
+	snaps, err := snapshotRepo.FindAppliedByBulkRequestID(ctx, parentID.Hex())
+	if err != nil {
+		log.Printf("rollback: failed to load snapshots for %s: %v", parentID.Hex(), err)
+		http.Error(w, "failed to load snapshots", http.StatusInternalServerError)
+		return
+	}
+	if len(snaps) == 0 {
+		http.Error(w, "nothing to roll back", http.StatusConflict)
+		return
+	}
+
+	req := newBulkRequest(r, "rollback", fmt.Sprintf("rollback of %s", parentID.Hex()))
+	req.Type = parent.Type
+	req.BaseType = parent.BaseType
+	req.SchemaID = parent.SchemaID
+	req.CategoryID = parent.CategoryID
+	req.ParentRequestID = parentID.Hex()
+
+	items := make([]model.BulkItem, len(snaps))
+	for i, snap := range snaps {
+		// the fields being restored, rendered for the report
+		restore := map[string]string{}
+		for k, v := range snap.Before {
+			if v != nil {
+				restore[k] = fmt.Sprint(v)
+			}
+		}
+		for _, k := range snap.Missing {
+			restore[k] = ""
+		}
+
+		items[i] = model.BulkItem{
+			Value:        snap.Value,
+			Type:         snap.Type,
+			BaseType:     snap.BaseType,
+			Status:       "pending",
+			UpdateFields: restore,
+		}
+	}
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		// items are inserted in snapshot order, so index i belongs to snaps[i]
+		byItem := make(map[string]model.BulkItemSnapshot, len(items))
+		for i, item := range items {
+			byItem[item.ID.Hex()] = snaps[i]
+		}
+
+		processor := worker.NewRollbackProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+		)
+		processor.Process(ctx, req, items, byItem)
+	})
+}
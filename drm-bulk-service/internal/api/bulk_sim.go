@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+// simBatchColumns is the positional layout of a SIM batch file
+var simBatchColumns = []string{"ICCID", "IMSI", "MSISDN", "PIN1", "PUK1", "PIN2", "PUK2"}
+
+/*
+===========================
+POST /v1/drm-bulk/resources/sim-batch
+Bulk SIM PAIRING entrypoint
+
+Per row: creates the SIM card (PhysicalResource, value=ICCID), the IMSI
+and the MSISDN (LogicalResource), makes IMSI/MSISDN rely on the SIM and
+bundles both on the SIM. Each row is all-or-nothing: if any step fails,
+the resources already created for that row are removed again.
+
+PIN/PUK values are never stored in bulk_items: they are handed to the
+processor in memory and their columns are blank in the stored row.
+
+CSV format (PIN2/PUK2 optional):
+
+	ICCID,IMSI,MSISDN,PIN1,PUK1,PIN2,PUK2
+	8998101234567890123,432111234567890,989121234567,1234,12345678,,
+
+Form-data:
+
+	type            = SIM (physical resource type, default "SIM")
+	resourceStatus  = Available (status of the created resources)
+	skipLines       = 1
+	user*           = user info
+
+===========================
+*/
+func (s *Server) handleBulkSimUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, header, ok := parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	skip, _ := strconv.Atoi(r.FormValue("skipLines"))
+
+	resourceStatus := r.FormValue("resourceStatus")
+	if resourceStatus == "" {
+		resourceStatus = lifecycle.StatusAvailable
+	}
+	if !lifecycle.IsKnown(resourceStatus) {
+		http.Error(w, "unknown resourceStatus: "+resourceStatus, http.StatusBadRequest)
+		return
+	}
+
+	req := newBulkRequest(r, "sim", header.Filename)
+	if req.Type == "" {
+		req.Type = "SIM"
+	}
+	req.BaseType = "PhysicalResource"
+
+	csvHeader, rows, err := readCSVRows(file, skip)
+	if err != nil {
+		http.Error(w, "failed to read CSV", http.StatusBadRequest)
+		return
+	}
+	setInputHeader(&req, csvHeader, simBatchColumns...)
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		if len(row.Fields) < 3 {
+			continue
+		}
+
+		var chars []model.ResourceCharacteristic
+		for i, code := range simBatchColumns {
+			if v := row.field(i); v != "" {
+				chars = append(chars, model.ResourceCharacteristic{Code: code, Name: code, Value: v})
+			}
+		}
+
+		items = append(items, model.BulkItem{
+			Value:                  row.field(0),
+			Type:                   req.Type,
+			BaseType:               req.BaseType,
+			Status:                 "pending",
+			ResourceCharacteristic: chars,
+			LineNumber:             row.Line,
+			RawRow:                 row.Fields,
+		})
+	}
+
+	pipeline, err := s.ingestPipeline(r.Context(), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pipeline.Run(items)
+	secrets := splitSecrets(items)
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		// items keep their order, so secrets[i] belongs to items[i]
+		for i := range items {
+			items[i].ResourceCharacteristic = append(items[i].ResourceCharacteristic, secrets[i]...)
+		}
+
+		processor := worker.NewSimBatchProcessor(
+			s.invClient,
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+			resourceStatus,
+		)
+		processor.Process(ctx, req, items)
+	})
+}
+
+// splitSecrets removes the PIN/PUK characteristics from items and blanks their
+// columns in RawRow, so they are never persisted. The removed characteristics
+// are returned indexed like items
+func splitSecrets(items []model.BulkItem) [][]model.ResourceCharacteristic {
+	secrets := make([][]model.ResourceCharacteristic, len(items))
+	for i := range items {
+		var public []model.ResourceCharacteristic
+		for _, rc := range items[i].ResourceCharacteristic {
+			if model.SecretCharacteristics[rc.Code] {
+				secrets[i] = append(secrets[i], rc)
+				continue
+			}
+			public = append(public, rc)
+		}
+		items[i].ResourceCharacteristic = public
+
+		raw := append([]string(nil), items[i].RawRow...)
+		for col, code := range simBatchColumns {
+			if col < len(raw) && model.SecretCharacteristics[code] {
+				raw[col] = ""
+			}
+		}
+		items[i].RawRow = raw
+	}
+	return secrets
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+	"drm-bulk-service/internal/worker"
+)
+
+/*
+===========================
+POST /v1/drm-bulk/resources/status
+Bulk STATUS CHANGE entrypoint
+
+Every row is validated against the lifecycle state machine
+(STATUS_TRANSITIONS) before inventory is touched.
+
+CSV format (resourceStatus and type are optional per row):
+
+	value,resourceStatus,type
+	800700000,Retired
+	800700008,Available,MSISDN
+
+Form-data:
+
+	type          = MSISDN (default type when the row has none)
+	baseType      = LogicalResource | PhysicalResource
+	targetStatus  = Retired (default status when the row has none)
+	skipLines     = 1
+	user*         = user info
+
+===========================
+*/
+func (s *Server) handleBulkStatusUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, header, ok := parseUpload(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	skip, _ := strconv.Atoi(r.FormValue("skipLines"))
+
+	targetStatus := r.FormValue("targetStatus")
+	if targetStatus != "" && !lifecycle.IsKnown(targetStatus) {
+		http.Error(w, "unknown targetStatus: "+targetStatus, http.StatusBadRequest)
+		return
+	}
+
+	req := newBulkRequest(r, "status", header.Filename)
+
+	csvHeader, rows, err := readCSVRows(file, skip)
+	if err != nil {
+		http.Error(w, "failed to read CSV", http.StatusBadRequest)
+		return
+	}
+	setInputHeader(&req, csvHeader, "value", "resourceStatus", "type")
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		value := row.field(0)
+		if value == "" {
+			continue
+		}
+
+		toStatus := row.field(1)
+		if toStatus == "" {
+			toStatus = targetStatus
+		}
+		itemType := row.field(2)
+		if itemType == "" {
+			itemType = req.Type
+		}
+
+		items = append(items, model.BulkItem{
+			Value:    value,
+			Type:     itemType,
+			BaseType: req.BaseType,
+			Status:   "pending",
+			ToStatus: toStatus,
+
+			LineNumber: row.Line,
+			RawRow:     row.Fields,
+		})
+	}
+
+	s.lookupPipeline().Run(items)
+
+	s.submitBulk(w, r, &req, items, func(ctx context.Context, req model.BulkRequest, items []model.BulkItem) {
+		processor := worker.NewStatusProcessor(
+			s.bulkItemRepo,
+			s.bulkReqRepo,
+			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			s.lifecycle,
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
+		)
+		processor.Process(ctx, req, items)
+	})
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -19,17 +18,28 @@ import (
 POST /v1/drm-bulk/resources/update
 Bulk UPDATE entrypoint
 
-CSV format:
+CSV format (positional):
 
-	value,name
-	800700000,Router
-	800700008,Router2
+	value,type,name
+	800700000,Router,Customer A Router
+	800700008,Router,Customer B Router
+
+CSV format (header-driven, when the skipped header line starts with "value"):
+
+	value,type,name,endOperatingDate,expected.resourceStatus,expected.updatedAt
+	800700000,Router,Customer A,2025-12-31T00:00:00Z,Available,2024-05-01T10:00:00Z
+
+Every column other than value/type is a field to $set: name, description,
+startOperatingDate, endOperatingDate or resourceRecycleDate (dates as RFC3339
+or epoch milliseconds, empty clears). Any other column rejects the file.
+"expected.<field>" columns are optimistic concurrency guards added to the
+update filter. Rows whose document no longer matches are reported as "conflict".
 
 Form-data:
 
 	type       = Router
 	baseType   = LogicalResource
-	schemaId   = ...
+	schemaId   = ... (its validation rules run on every row)
 	categoryId = ...
 	skipLines  = 1
 	user*      = user info
@@ -59,6 +69,7 @@ func (s *Server) handleBulkUpdateUpload(w http.ResponseWriter, r *http.Request)
 
 	// Build BulkRequest metadata
 	req := model.BulkRequest{
+		Operation:  "update",
 		Type:       r.FormValue("type"),
 		BaseType:   r.FormValue("baseType"),
 		FileName:   header.Filename,
@@ -74,67 +85,39 @@ func (s *Server) handleBulkUpdateUpload(w http.ResponseWriter, r *http.Request)
 
 	ctx := r.Context()
 
-	// Insert BulkRequest document
-	if err := s.bulkReqRepo.Insert(ctx, &req); err != nil {
-		http.Error(w, "failed to create request", http.StatusInternalServerError)
+	// Read CSV content: value,type,name (or header-driven, see parseUpdateRows)
+	csvHeader, rows, err := readCSVRows(file, skip)
+	if err != nil {
+		http.Error(w, "failed to read CSV", http.StatusBadRequest)
+		return
+	}
+	setInputHeader(&req, csvHeader, "value", "type", "name")
+	if err := checkUpdateColumns(csvHeader); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Read CSV content: value,name
-	scanner := bufio.NewScanner(file)
-	line := 0
-	var items []model.BulkItem
-
-	for scanner.Scan() {
-		line++
-		if line <= skip {
-			// skip header
-			continue
-		}
-
-		text := scanner.Text()
-		parts := strings.Split(text, ",")
-		// we expect 3 columns: value,type,name
-		if len(parts) < 3 {
-			continue
-		}
-
-		value := strings.TrimSpace(parts[0])   // value column
-		rowType := strings.TrimSpace(parts[1]) // type column from CSV
-		rowName := strings.TrimSpace(parts[2]) // name column from CSV
-
-		// decide which type to use for this item:
-		// - if you pass type in form-data (req.Type), use that
-		// - otherwise fall back to type from CSV
-		itemType := req.Type
-		if itemType == "" {
-			itemType = rowType
-		}
-
-		updateFields := map[string]string{
-			"name": rowName,
-		}
-
-		item := model.BulkItem{
-			BulkRequestID: req.ID,
-			Value:         value,
-			Type:          itemType,
-			BaseType:      req.BaseType,
-			Status:        "pending",
-			UpdateFields:  updateFields,
-		}
-		items = append(items, item)
+	// Values are normalized like creates store them, then the schema rules run
+	// (e.g. endOperatingDate > startOperatingDate) on the update fields
+	pipeline := s.lookupPipeline()
+	if err := s.addSchemaRules(ctx, r, pipeline); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := scanner.Err(); err != nil {
-		http.Error(w, "failed to read CSV", http.StatusBadRequest)
+	// Insert BulkRequest document
+	if err := s.bulkReqRepo.Insert(ctx, &req); err != nil {
+		http.Error(w, "failed to create request", http.StatusInternalServerError)
 		return
 	}
 
+	items := parseUpdateRows(csvHeader, rows, req)
+
 	if len(items) == 0 {
 		http.Error(w, "no valid rows found in CSV", http.StatusBadRequest)
 		return
 	}
+	pipeline.Run(items)
 
 	// Insert BulkItems
 	if err := s.bulkItemRepo.InsertMany(ctx, items); err != nil {
@@ -159,6 +142,7 @@ func (s *Server) handleBulkUpdateUpload(w http.ResponseWriter, r *http.Request)
 			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
 			logicalUpdater, // logical updater
 			nil,            // no physical updater yet
+			repository.NewBulkItemSnapshotRepository(s.db), // before-images for rollback
 		)
 
 		processor.Process(bgCtx, req, items)
@@ -169,4 +153,95 @@ func (s *Server) handleBulkUpdateUpload(w http.ResponseWriter, r *http.Request)
 		"requestId": req.ID.Hex(),
 		"status":    "pending",
 	})
-}
\ No newline at end of file
+}
+
+// updateHeaderMode reports whether the update file names its columns in a header line
+func updateHeaderMode(header []string) bool {
+	return len(header) > 0 && strings.EqualFold(strings.TrimSpace(header[0]), "value")
+}
+
+// checkUpdateColumns rejects header columns a bulk update may not set
+func checkUpdateColumns(header []string) error {
+	if !updateHeaderMode(header) {
+		return nil
+	}
+	for _, col := range header {
+		col = strings.TrimSpace(col)
+		if col == "" || strings.EqualFold(col, "value") || strings.EqualFold(col, "type") || strings.HasPrefix(col, "expected.") {
+			continue
+		}
+		if err := repository.CheckUpdateField(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseUpdateRows turns update CSV rows into BulkItems.
+// With a header starting with "value" the columns are named by the header,
+// otherwise the legacy positional layout value,type,name is used
+func parseUpdateRows(header []string, rows []csvRow, req model.BulkRequest) []model.BulkItem {
+	named := updateHeaderMode(header)
+
+	var items []model.BulkItem
+	for _, row := range rows {
+		var value, rowType string
+		updateFields := map[string]string{}
+		expected := map[string]string{}
+
+		if named {
+			for i, col := range header {
+				col = strings.TrimSpace(col)
+				if col == "" || i >= len(row.Fields) {
+					continue
+				}
+				switch {
+				case strings.EqualFold(col, "value"):
+					value = row.field(i)
+				case strings.EqualFold(col, "type"):
+					rowType = row.field(i)
+				case strings.HasPrefix(col, "expected."):
+					expected[strings.TrimPrefix(col, "expected.")] = row.field(i)
+				default:
+					updateFields[col] = row.field(i)
+				}
+			}
+		} else {
+			// we expect 3 columns: value,type,name
+			if len(row.Fields) < 3 {
+				continue
+			}
+			value = row.field(0)   // value column
+			rowType = row.field(1) // type column from CSV
+			updateFields["name"] = row.field(2)
+		}
+
+		if value == "" {
+			continue
+		}
+
+		// decide which type to use for this item:
+		// - if you pass type in form-data (req.Type), use that
+		// - otherwise fall back to type from CSV
+		itemType := req.Type
+		if itemType == "" {
+			itemType = rowType
+		}
+
+		item := model.BulkItem{
+			BulkRequestID: req.ID,
+			Value:         value,
+			Type:          itemType,
+			BaseType:      req.BaseType,
+			Status:        "pending",
+			UpdateFields:  updateFields,
+			LineNumber:    row.Line,
+			RawRow:        row.Fields,
+		}
+		if len(expected) > 0 {
+			item.Expected = expected
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -1,17 +1,19 @@
 package api
 
 import (
-	"bufio"
 	"context"
 	"drm-bulk-service/internal/config"
+	"drm-bulk-service/internal/export"
 	grpcclient "drm-bulk-service/internal/grpc"
 	"drm-bulk-service/internal/health"
+	"drm-bulk-service/internal/ingest"
+	"drm-bulk-service/internal/lifecycle"
 	"drm-bulk-service/internal/model"
 	"drm-bulk-service/internal/report"
 	"drm-bulk-service/internal/repository"
 	"drm-bulk-service/internal/worker"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -20,11 +22,9 @@ import (
 	"strings"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 /*
@@ -37,6 +37,9 @@ It holds:
 - gRPC inventory client
 - Mongo database handle (for GridFS, etc.)
 - schemaRepo: to load schema documents by schemaId
+- lifecycle: allowed resourceStatus transitions
+- classifier: MobileClass pattern rules for create requests
+- normalizer: identifier canonicalization / check-digit validation at ingest
 */
 type Server struct {
 	cfg          config.Config
@@ -48,6 +51,9 @@ type Server struct {
 	db           *mongo.Database
 
 	schemaRepo *repository.SchemaRepository // access to schema collection
+	lifecycle  *lifecycle.StateMachine      // resourceStatus transition rules
+	classifier *ingest.Classifier           // MobileClass rules (classify=override|validate)
+	normalizer *ingest.Normalizer           // MSISDN / ICCID / IMSI / IMEI normalization
 }
 
 /*
@@ -96,6 +102,9 @@ func NewServer(
 	invClient *grpcclient.InventoryClient,
 	db *mongo.Database,
 	schemaRepo *repository.SchemaRepository,
+	sm *lifecycle.StateMachine,
+	classifier *ingest.Classifier,
+	normalizer *ingest.Normalizer,
 ) *Server {
 	s := &Server{
 		cfg:          cfg,
@@ -106,6 +115,9 @@ func NewServer(
 		invClient:    invClient,
 		db:           db,
 		schemaRepo:   schemaRepo,
+		lifecycle:    sm,
+		classifier:   classifier,
+		normalizer:   normalizer,
 	}
 	s.routes() // register routes
 	return s
@@ -122,17 +134,52 @@ func (s *Server) routes() {
 	// POST: bulk update
 	s.mux.HandleFunc("/v1/drm-bulk/resources/update", s.handleBulkUpdateUpload)
 
-	// GET: request details OR report download
+	// POST: bulk status change (lifecycle validated)
+	s.mux.HandleFunc("/v1/drm-bulk/resources/status", s.handleBulkStatusUpload)
+
+	// POST: bulk retire / delete (file or value range)
+	s.mux.HandleFunc("/v1/drm-bulk/resources/retire", s.handleBulkRetire)
+	s.mux.HandleFunc("/v1/drm-bulk/resources/delete", s.handleBulkDelete)
+
+	// POST: bulk reserve for a customer, released by the reservation sweeper on expiry
+	s.mux.HandleFunc("/v1/drm-bulk/resources/reserve", s.handleBulkReserve)
+
+	// POST: allocate N Available resources by criteria (JSON body)
+	s.mux.HandleFunc("/v1/drm-bulk/resources/allocate", s.handleBulkAllocate)
+
+	// POST: bulk relationship creation
+	s.mux.HandleFunc("/v1/drm-bulk/resources/link", s.handleBulkLinkUpload)
+
+	// POST: SIM batch (ICCID + IMSI + MSISDN pairing)
+	s.mux.HandleFunc("/v1/drm-bulk/resources/sim-batch", s.handleBulkSimUpload)
+
+	// GET: request details OR report download; POST: {id}/rollback
 	s.mux.HandleFunc("/v1/drm-bulk/resources/", s.handleGet)
 
 	// GET: bulk export
 	s.mux.HandleFunc("/v1/drm-bulk/resources/export", s.handleBulkExport)
+
+	// POST: asynchronous export job; GET: {id} status and {id}/download
+	s.mux.HandleFunc("/v1/drm-bulk/exports", s.handleExportCreate)
+	s.mux.HandleFunc("/v1/drm-bulk/exports/", s.handleExportGet)
+
+	// POST: run the retention purge now (admin)
+	s.mux.HandleFunc("/v1/drm-bulk/admin/retention/purge", s.handleRetentionPurge)
 }
 
 /*
 ===========================
 POST /v1/drm-bulk/resources
 Bulk CREATE entrypoint
+
+CSV format:
+
+	MSISDN,MobileClass[,companionValue]
+
+Optional form-data companionType / companionBaseType create a second
+resource per row (relying on the first). The row is one unit of work:
+if the companion fails, the first resource is removed again and the
+item is reported as "rolled_back" with the original error.
 ===========================
 */
 func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
@@ -162,6 +209,7 @@ func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 	// - type / baseType  (resource type)
 	// - schemaId / categoryId from form-data
 	req := model.BulkRequest{
+		Operation:  "create",
 		Type:       r.FormValue("type"),
 		BaseType:   r.FormValue("baseType"),
 		FileName:   header.Filename,
@@ -177,63 +225,79 @@ func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Optional: load schema by schemaId if provided
-	// Later (Phase 2) we will pass this schema into the worker to shape the gRPC payload
-	if req.SchemaID != "" {
-		schema, err := s.schemaRepo.GetByID(ctx, req.SchemaID)
-		if err != nil {
-			log.Printf("Failed to load schema %s: %v", req.SchemaID, err)
-		} else {
-			log.Printf("Loaded schema %s (%s)", req.SchemaID, schema.Name)
-			// TODO (Phase 2): use this schema in worker to build LogicalResource payload
-		}
+	// Ingest stages run on every row before it is stored.
+	// The schema (schemaId) contributes its validation rules
+	// Later (Phase 2) we will pass the schema into the worker to shape the gRPC payload
+	pipeline, err := s.ingestPipeline(ctx, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Insert BulkRequest into MongoDB
-	if err := s.bulkReqRepo.Insert(ctx, &req); err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+	// Companion resource type per row (optional, see column 2 below)
+	companionType := r.FormValue("companionType")
+	companionBaseType := r.FormValue("companionBaseType")
+	if companionBaseType == "" {
+		companionBaseType = "LogicalResource"
+	}
+
+	// Read CSV rows (line numbers are kept for the report)
+	csvHeader, rows, err := readCSVRows(file, skip)
+	if err != nil {
+		http.Error(w, "Failed to read CSV", http.StatusBadRequest)
 		return
 	}
+	setInputHeader(&req, csvHeader, "MSISDN", "MobileClass", "companionValue")
 
-	// Read CSV file line by line and build BulkItem slice
-	scanner := bufio.NewScanner(file)
 	var items []model.BulkItem
-	line := 0
-
-	for scanner.Scan() {
-		line++
-		if line <= skip {
-			// skip header lines
-			continue
-		}
-
-		text := scanner.Text()
-		parts := strings.Split(text, ",")
-		if len(parts) < 2 {
+	for _, row := range rows {
+		if len(row.Fields) < 2 {
 			continue
 		}
 
 		// For creation we still interpret:
-		//   parts[0] = MSISDN
-		//   parts[1] = MobileClass
+		//   column 0 = MSISDN
+		//   column 1 = MobileClass
 		// and store them as resourceCharacteristics
-		items = append(items, model.BulkItem{
-			BulkRequestID: req.ID,
-			Value:         text, // you can change to parts[0] if you want
-			Type:          req.Type,
-			BaseType:      req.BaseType,
-			Status:        "pending",
+		item := model.BulkItem{
+			Value:      row.field(0),
+			Type:       req.Type,
+			BaseType:   req.BaseType,
+			Status:     "pending",
+			LineNumber: row.Line,
+			RawRow:     row.Fields,
 			ResourceCharacteristic: []model.ResourceCharacteristic{
-				{Code: "MSISDN", Value: parts[0]},
-				{Code: "MobileClass", Value: parts[1]},
+				{Code: "MSISDN", Value: row.field(0)},
+				{Code: "MobileClass", Value: row.field(1)},
 			},
-		})
+		}
+
+		// Optional companion resource created in the same unit of work:
+		//   column 2 = companion value (e.g. the router's logical IP)
+		if companionType != "" && row.field(2) != "" {
+			item.Companion = &model.CompanionResource{
+				Type:     companionType,
+				BaseType: companionBaseType,
+				Value:    row.field(2),
+			}
+		}
+
+		items = append(items, item)
 	}
 
-	if err := scanner.Err(); err != nil {
-		http.Error(w, "Failed to read CSV", http.StatusBadRequest)
+	// Insert BulkRequest into MongoDB
+	if err := s.bulkReqRepo.Insert(ctx, &req); err != nil {
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
 		return
 	}
+	for i := range items {
+		items[i].BulkRequestID = req.ID
+	}
+
+	// Rejected rows are stored as "failure" and skipped by the worker
+	if rejected := pipeline.Run(items); rejected > 0 {
+		log.Printf("Ingest rejected %d of %d rows (request %s)", rejected, len(items), req.ID.Hex())
+	}
 
 	// Insert all BulkItems into MongoDB
 	if err := s.bulkItemRepo.InsertMany(ctx, items); err != nil {
@@ -253,6 +317,8 @@ func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 			s.bulkItemRepo,
 			s.bulkReqRepo,
 			report.NewService(s.bulkItemRepo, s.reportRepo, s.db),
+			repository.NewInventoryLogicalRepository(s.db),
+			repository.NewInventoryPhysicalRepository(s.db),
 		)
 
 		processor.Process(bgCtx, req, items)
@@ -269,9 +335,24 @@ func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 ===========================
 GET /v1/drm-bulk/resources/{id}
 GET /v1/drm-bulk/resources/{id}/report
+GET /v1/drm-bulk/resources/{id}/reports
+POST /v1/drm-bulk/resources/{id}/report/regenerate
+POST /v1/drm-bulk/resources/{id}/rollback
 ===========================
 */
 func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/rollback") {
+		s.handleBulkRollback(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/report/regenerate") {
+		s.handleReportRegenerate(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/reports") {
+		s.handleReportList(w, r)
+		return
+	}
 	if strings.HasSuffix(r.URL.Path, "/report") {
 		s.handleReportDownload(w, r)
 		return
@@ -301,7 +382,14 @@ func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
 /*
 ===========================
 GET /v1/drm-bulk/resources/{id}/report
-Streams CSV report from GridFS
+Streams the report from GridFS
+
+Query params:
+
+	format  = csv | json | ndjson | xlsx  (default: csv; others are generated on first request)
+	only    = failures  (failed rows in the uploaded format, CSV only)
+	version = report version (default: latest, see /reports)
+	partial = true  (current item states while the job runs, not stored)
 ===========================
 */
 func (s *Server) handleReportDownload(w http.ResponseWriter, r *http.Request) {
@@ -316,30 +404,87 @@ func (s *Server) handleReportDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	format, err := report.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("partial") == "true" {
+		s.handlePartialReport(w, r, reqID, format)
+		return
+	}
+
 	ctx := r.Context()
-	reportDoc, err := s.reportRepo.FindByRequestID(ctx, reqID.Hex())
+	var reportDoc *model.BulkReport
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			http.Error(w, "version must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		reportDoc, err = s.reportRepo.FindByRequestIDAndVersion(ctx, reqID.Hex(), version)
+	} else {
+		reportDoc, err = s.reportRepo.FindByRequestID(ctx, reqID.Hex())
+	}
 	if err != nil || reportDoc == nil {
 		http.Error(w, "Report not ready", http.StatusNotFound)
 		return
 	}
 
+	var fileID primitive.ObjectID
+	fileName := report.FileName(reqID.Hex(), reportDoc.Version, format)
+
+	// ?only=failures returns the failed rows in the uploaded format
+	if r.URL.Query().Get("only") == "failures" {
+		if format != report.FormatCSV {
+			http.Error(w, "The failures report is only available as csv", http.StatusBadRequest)
+			return
+		}
+		if reportDoc.FailuresFileID == nil {
+			http.Error(w, "No failures report for this request", http.StatusNotFound)
+			return
+		}
+		fileID, fileName = *reportDoc.FailuresFileID, report.FailuresFileName(reqID.Hex(), reportDoc.Version)
+	} else {
+		req, err := s.bulkReqRepo.GetByID(ctx, reqID.Hex())
+		if err != nil {
+			http.Error(w, "Bulk request not found", http.StatusNotFound)
+			return
+		}
+		fileID, err = report.NewService(s.bulkItemRepo, s.reportRepo, s.db).File(ctx, *req, reportDoc, format)
+		if errors.Is(err, report.ErrItemsPurged) {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
+		if errors.Is(err, report.ErrVersionSuperseded) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to generate report:", err)
+			http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	bucket, err := gridfs.NewBucket(s.db)
 	if err != nil {
 		http.Error(w, "Storage error", http.StatusInternalServerError)
 		return
 	}
 
-	stream, err := bucket.OpenDownloadStream(reportDoc.FileID)
+	stream, err := bucket.OpenDownloadStream(fileID)
 	if err != nil {
 		http.Error(w, "Failed to read report", http.StatusInternalServerError)
 		return
 	}
 	defer stream.Close()
 
-	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Type", report.ContentType(format))
 	w.Header().Set(
 		"Content-Disposition",
-		fmt.Sprintf("attachment; filename=bulk_report_%s.csv", reqID.Hex()),
+		"attachment; filename="+fileName,
 	)
 
 	// Stream GridFS file directly to HTTP response
@@ -359,7 +504,8 @@ func (s *Server) Start() error {
 /*
 ===========================
 GET /v1/drm-bulk/resources/export
-Bulk EXPORT (synchronous, CSV streaming)
+Bulk EXPORT (synchronous, CSV streaming; see POST /v1/drm-bulk/exports
+for large exports that run in the background)
 
 Query params:
 
@@ -367,10 +513,24 @@ Query params:
 	limit           = number of rows to export (default: 1000)
 	fields          = comma-separated list of fields, e.g. "value,name,baseType,category"
 	                  - "value" is always included (mandatory)
+	                  - dot paths reach embedded documents and arrays: cost.taxedValue, relatedParty.0.name
+	                  - char:<Code> is the value of that resourceCharacteristic, e.g. char:MobileClass
+	arrayFormat     = json | flat  arrays of objects / embedded documents as JSON (default)
+	                  or flattened with indices (0.name=ACME;0.role=Customer)
 	type            = filter by resource type (e.g. "Router", "MSISDN")
 	resourceStatus  = filter by resourceStatus (if your documents have it)
-	valueFrom       = lower bound for value (inclusive)  (optional, string compare)
+	valueFrom       = lower bound for value (inclusive)  (optional)
 	valueTo         = upper bound for value (inclusive)  (optional)
+	numericOrder    = true | false  compare and sort values as numbers
+	                  (default: true for NUMERIC_VALUE_TYPES, or digit-only bounds without type)
+	category        = category membership, comma separated (any of)
+	businessType    = businessType membership, comma separated (any of)
+	createdFrom/To  = createdAt range (RFC3339 or 2006-01-02)
+	updatedFrom/To  = updatedAt range
+	relatedParty    = relatedParty.name
+	char            = characteristic Code:Value, e.g. MobileClass:Gold (repeatable)
+	match           = all | any  (default: all) how the filters above combine
+	filter          = JSON filter tree with "and"/"or" (see model.ExportFilter)
 
 Examples:
 
@@ -378,6 +538,8 @@ Examples:
 
 	/v1/drm-bulk/resources/export?baseType=LogicalResource&valueFrom=800700000&valueTo=800700999&fields=value,name,baseType,type
 
+	/v1/drm-bulk/resources/export?type=MSISDN&category=Gold,Platinum&char=MobileClass:Gold&relatedParty=ACME&match=any
+
 ===========================
 */
 func (s *Server) handleBulkExport(w http.ResponseWriter, r *http.Request) {
@@ -387,85 +549,14 @@ func (s *Server) handleBulkExport(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	q := r.URL.Query()
-
-	// BaseType â†’ select collection
-	baseType := q.Get("baseType")
-	if baseType == "" {
-		baseType = "LogicalResource"
-	}
-
-	var coll *mongo.Collection
-	switch baseType {
-	case "PhysicalResource":
-		coll = s.db.Collection("physicalresources")
-	default:
-		// default to logicalresources
-		coll = s.db.Collection("logicalresources")
-	}
-
-	// Limit
-	limit := int64(1000)
-	if lStr := q.Get("limit"); lStr != "" {
-		if l, err := strconv.ParseInt(lStr, 10, 64); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	// Fields (dynamic header). "value" must always be present
-	fieldsParam := q.Get("fields")
-	if fieldsParam == "" {
-		fieldsParam = "value" // default only value
-	}
-	rawFields := strings.Split(fieldsParam, ",")
-	var fields []string
-	hasValue := false
-	for _, f := range rawFields {
-		f = strings.TrimSpace(f)
-		if f == "" {
-			continue
-		}
-		if f == "value" {
-			hasValue = true
-		}
-		fields = append(fields, f)
-	}
-	if !hasValue {
-		fields = append([]string{"value"}, fields...)
-	}
-
-	// Build Mongo filter
-	filter := bson.M{}
-	// optional baseType field in docs
-	filter["baseType"] = baseType
-
-	if t := q.Get("type"); t != "" {
-		filter["type"] = t
-	}
-	if rs := q.Get("resourceStatus"); rs != "" {
-		filter["resourceStatus"] = rs
-	}
-
-	// Optional range on value
-	valueFrom := q.Get("valueFrom")
-	valueTo := q.Get("valueTo")
-	if valueFrom != "" || valueTo != "" {
-		rangeCond := bson.M{}
-		if valueFrom != "" {
-			rangeCond["$gte"] = valueFrom
-		}
-		if valueTo != "" {
-			rangeCond["$lte"] = valueTo
-		}
-		filter["value"] = rangeCond
+	query, err := export.ParseQuery(r.URL.Query(), export.DefaultSyncLimit, s.numericValueTypes())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Query Mongo
-	findOpts := options.Find().SetLimit(limit)
-	// Optional: sort by value ascending
-	findOpts.SetSort(bson.D{{Key: "value", Value: 1}})
-
-	cursor, err := coll.Find(ctx, filter, findOpts)
+	svc := export.NewService(s.db)
+	cursor, err := svc.Find(ctx, query)
 	if err != nil {
 		log.Printf("export: Mongo Find error: %v", err)
 		http.Error(w, "failed to query resources", http.StatusInternalServerError)
@@ -480,63 +571,8 @@ func (s *Server) handleBulkExport(w http.ResponseWriter, r *http.Request) {
 		fmt.Sprintf(`attachment; filename="resource_export_%d.csv"`, time.Now().Unix()),
 	)
 
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-
-	// CSV header
-	if err := writer.Write(fields); err != nil {
-		log.Printf("export: write header error: %v", err)
-		http.Error(w, "failed to write csv header", http.StatusInternalServerError)
-		return
-	}
-
-	// 7) Stream rows
-	for cursor.Next(ctx) {
-		var doc bson.M
-		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("export: decode error: %v", err)
-			http.Error(w, "failed to decode resource", http.StatusInternalServerError)
-			return
-		}
-
-		row := make([]string, len(fields))
-		for i, f := range fields {
-			row[i] = extractField(doc, f)
-		}
-
-		if err := writer.Write(row); err != nil {
-			log.Printf("export: write row error: %v", err)
-			http.Error(w, "failed to write csv row", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	if err := cursor.Err(); err != nil {
-		log.Printf("export: cursor error: %v", err)
-		http.Error(w, "failed to iterate resources", http.StatusInternalServerError)
-		return
-	}
-}
-
-// extractField returns a string value for a given field name from a Mongo document
-// It supports simple top-level fields and array fields (joined by ';')
-func extractField(doc bson.M, field string) string {
-	v, ok := doc[field]
-	if !ok {
-		return ""
-	}
-
-	switch val := v.(type) {
-	case string:
-		return val
-	case []interface{}:
-		// e.g. category: [], businessType: []
-		parts := make([]string, 0, len(val))
-		for _, el := range val {
-			parts = append(parts, fmt.Sprint(el))
-		}
-		return strings.Join(parts, ";")
-	default:
-		return fmt.Sprint(v)
+	// Stream rows; once the first row is out an error can only be logged
+	if _, err := svc.WriteCSV(ctx, w, cursor, query); err != nil {
+		log.Printf("export: %v", err)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"drm-bulk-service/internal/ingest"
+	"drm-bulk-service/internal/model"
+)
+
+// csvRow is one data row of an uploaded CSV together with its line number in the file
+type csvRow struct {
+	Line   int
+	Fields []string
+}
+
+// field returns the trimmed column i, or "" when the row is shorter
+func (r csvRow) field(i int) string {
+	if i < len(r.Fields) {
+		return strings.TrimSpace(r.Fields[i])
+	}
+	return ""
+}
+
+// readCSVRows reads an uploaded CSV and skips the first `skip` lines.
+// The last skipped line is returned as the header (nil when nothing is skipped)
+func readCSVRows(in io.Reader, skip int) ([]string, []csvRow, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1 // rows may have a varying number of columns
+	reader.LazyQuotes = true
+
+	var header []string
+	var rows []csvRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if line <= skip {
+			header = record
+			continue
+		}
+		rows = append(rows, csvRow{Line: line, Fields: record})
+	}
+	return header, rows, nil
+}
+
+// setInputHeader records the header reports repeat for the uploaded columns: the
+// file's own header line when one was skipped, otherwise the documented column layout
+func setInputHeader(req *model.BulkRequest, header []string, layout ...string) {
+	if len(header) > 0 {
+		req.InputHeader = header
+		req.InputHasHeader = true
+		return
+	}
+	req.InputHeader = layout
+}
+
+// parseUpload parses the multipart form and returns the uploaded "file" part
+func parseUpload(w http.ResponseWriter, r *http.Request) (multipart.File, *multipart.FileHeader, bool) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "CSV file is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return file, header, true
+}
+
+// newBulkRequest builds the BulkRequest master record from the common form fields
+func newBulkRequest(r *http.Request, operation, fileName string) model.BulkRequest {
+	return model.BulkRequest{
+		Operation:  operation,
+		Type:       r.FormValue("type"),
+		BaseType:   r.FormValue("baseType"),
+		FileName:   fileName,
+		Status:     "pending",
+		SchemaID:   r.FormValue("schemaId"),
+		CategoryID: r.FormValue("categoryId"),
+
+		UserName:     r.FormValue("userName"),
+		UserRole:     r.FormValue("userRole"),
+		UserType:     r.FormValue("userType"),
+		UserBaseType: r.FormValue("userBaseType"),
+	}
+}
+
+// submitBulk persists the BulkRequest and its BulkItems, starts run in the background
+// and answers with the requestId immediately
+func (s *Server) submitBulk(
+	w http.ResponseWriter,
+	r *http.Request,
+	req *model.BulkRequest,
+	items []model.BulkItem,
+	run func(ctx context.Context, req model.BulkRequest, items []model.BulkItem),
+) {
+	if len(items) == 0 {
+		http.Error(w, "no valid rows found in CSV", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := s.bulkReqRepo.Insert(ctx, req); err != nil {
+		http.Error(w, "failed to create request", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range items {
+		items[i].BulkRequestID = req.ID
+	}
+	if err := s.bulkItemRepo.InsertMany(ctx, items); err != nil {
+		http.Error(w, "failed to save items", http.StatusInternalServerError)
+		return
+	}
+
+	_ = s.bulkReqRepo.UpdateTotalCount(ctx, req.ID.Hex(), len(items))
+
+	go run(context.Background(), *req, items)
+
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"requestId": req.ID.Hex(),
+		"status":    "pending",
+	})
+}
+
+// readValueRows returns the rows of an optional uploaded CSV ("file"), or, when no
+// file is sent, one row per value of the numeric range valueFrom..valueTo.
+// The returned name is the uploaded file name or a description of the range;
+// the header is the file's skipped header line, if any
+func readValueRows(w http.ResponseWriter, r *http.Request) (string, []string, []csvRow, bool) {
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return "", nil, nil, false
+	}
+
+	if file, fileHeader, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+
+		skip, _ := strconv.Atoi(r.FormValue("skipLines"))
+		header, rows, err := readCSVRows(file, skip)
+		if err != nil {
+			http.Error(w, "failed to read CSV", http.StatusBadRequest)
+			return "", nil, nil, false
+		}
+		return fileHeader.Filename, header, rows, true
+	}
+
+	valueFrom, valueTo := r.FormValue("valueFrom"), r.FormValue("valueTo")
+	if valueFrom == "" || valueTo == "" {
+		http.Error(w, "either a CSV file or valueFrom/valueTo is required", http.StatusBadRequest)
+		return "", nil, nil, false
+	}
+
+	values, err := expandRange(valueFrom, valueTo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", nil, nil, false
+	}
+
+	rows := make([]csvRow, len(values))
+	for i, v := range values {
+		rows[i] = csvRow{Fields: []string{v}}
+	}
+	return fmt.Sprintf("range %s-%s", valueFrom, valueTo), nil, rows, true
+}
+
+// lookupPipeline builds the ingest stages of an operation on existing resources
+// (status, update, retire / delete, reserve, link): their values are normalized
+// the same way creates store them
+func (s *Server) lookupPipeline() *ingest.Pipeline {
+	return ingest.NewPipeline(ingest.NewLookupStage(s.normalizer))
+}
+
+// ingestPipeline builds the ingest stages for a request: identifier normalization,
+// MobileClass classification when classify=override|validate is set, then the
+// validation rules of the schema given by schemaId
+func (s *Server) ingestPipeline(ctx context.Context, r *http.Request) (*ingest.Pipeline, error) {
+	pipeline := ingest.NewPipeline(ingest.NewNormalizeStage(s.normalizer))
+	if mode := r.FormValue("classify"); mode != "" {
+		stage, err := ingest.NewClassifyStage(s.classifier, mode)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Add(stage)
+	}
+
+	if err := s.addSchemaRules(ctx, r, pipeline); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// addSchemaRules appends the validation rules of the schema given by schemaId, if any
+func (s *Server) addSchemaRules(ctx context.Context, r *http.Request, pipeline *ingest.Pipeline) error {
+	schemaID := r.FormValue("schemaId")
+	if schemaID == "" {
+		return nil
+	}
+
+	schema, err := s.schemaRepo.GetByID(ctx, schemaID)
+	if err != nil {
+		return fmt.Errorf("failed to load schema %s: %w", schemaID, err)
+	}
+	log.Printf("Loaded schema %s (%s) rules=%d", schemaID, schema.Name, len(schema.Rules))
+
+	if len(schema.Rules) > 0 {
+		rules, err := ingest.ParseRules(schema.Rules)
+		if err != nil {
+			return fmt.Errorf("invalid rules in schema %s: %w", schemaID, err)
+		}
+		pipeline.Add(ingest.NewRuleStage(rules))
+	}
+	return nil
+}
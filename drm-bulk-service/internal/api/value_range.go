@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// maxRangeSize caps how many values a single valueFrom/valueTo range may expand to
+const maxRangeSize = 100000
+
+// expandRange lists every numeric value between from and to (inclusive).
+// Leading zeros of "from" are preserved by padding to its width
+func expandRange(from, to string) ([]string, error) {
+	from = strings.TrimSpace(from)
+	to = strings.TrimSpace(to)
+
+	start, ok := new(big.Int).SetString(from, 10)
+	if !ok || start.Sign() < 0 {
+		return nil, fmt.Errorf("valueFrom %q is not a number", from)
+	}
+	end, ok := new(big.Int).SetString(to, 10)
+	if !ok || end.Sign() < 0 {
+		return nil, fmt.Errorf("valueTo %q is not a number", to)
+	}
+	if start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("valueFrom %s is greater than valueTo %s", from, to)
+	}
+
+	size := new(big.Int).Sub(end, start)
+	if size.Cmp(big.NewInt(maxRangeSize-1)) > 0 {
+		return nil, fmt.Errorf("range %s..%s exceeds %d values", from, to, maxRangeSize)
+	}
+
+	width := len(from)
+	n := int(size.Int64()) + 1
+	values := make([]string, 0, n)
+	cur := new(big.Int).Set(start)
+	one := big.NewInt(1)
+	for i := 0; i < n; i++ {
+		values = append(values, fmt.Sprintf("%0*s", width, cur.String()))
+		cur.Add(cur, one)
+	}
+	return values, nil
+}
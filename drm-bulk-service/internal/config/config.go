@@ -6,6 +6,46 @@ type Config struct {
   Port     string
   MongoURI string
   MongoDB  string
+
+  // StatusTransitions overrides the resource lifecycle rules,
+  // e.g. "Available:Reserved,InUse;Reserved:Available" (see lifecycle.DefaultTransitions)
+  StatusTransitions string
+
+  // RecycleAfterDays is the default delay between retiring a resource and its resourceRecycleDate
+  RecycleAfterDays string
+
+  // ReservationDays is the default lifetime of a bulk reservation
+  ReservationDays string
+
+  // ReservationSweepInterval is how often expired reservations are released (Go duration, "0" disables)
+  ReservationSweepInterval string
+
+  // MobileClassRules are the MSISDN classification rules, e.g. "Gold:repeat>=5,sequence>=6;Silver:repeat>=4"
+  // (see ingest.DefaultClassRules)
+  MobileClassRules string
+
+  // MSISDNCountryCode is prefixed to national MSISDNs at ingest: those with a trunk 0 and,
+  // when MSISDNNationalLength is set, those with exactly that many digits
+  MSISDNCountryCode    string
+  MSISDNNationalLength string
+
+  // IMSIHomePLMNs restricts IMSIs to these MCC+MNC prefixes, comma separated (empty accepts any)
+  IMSIHomePLMNs string
+
+  // ItemRetentionDays / ReportRetentionDays is how long BulkItems of finished requests and
+  // report versions (with their GridFS files) are kept ("0" keeps them forever)
+  ItemRetentionDays   string
+  ReportRetentionDays string
+
+  // ExportRetentionDays is how long finished export jobs and their files are kept ("0" keeps them forever)
+  ExportRetentionDays string
+
+  // RetentionInterval is how often expired job data is purged (Go duration, "0" disables)
+  RetentionInterval string
+
+  // NumericValueTypes are the resource types whose values compare as numbers in export
+  // ranges, sorting and allocation, comma separated
+  NumericValueTypes string
 }
 
 func Load() Config {
@@ -13,6 +53,25 @@ func Load() Config {
     Port:     getEnv("PORT", "3035"),
     MongoURI: getEnv("MONGO_URI", "mongodb://localhost:27017/resourceDB"),
     MongoDB:  getEnv("MONGO_DB", "resourceDB"),
+
+    StatusTransitions: getEnv("STATUS_TRANSITIONS", ""),
+    RecycleAfterDays:  getEnv("RECYCLE_AFTER_DAYS", "90"),
+
+    ReservationDays:          getEnv("RESERVATION_DAYS", "30"),
+    ReservationSweepInterval: getEnv("RESERVATION_SWEEP_INTERVAL", "5m"),
+
+    MobileClassRules: getEnv("MOBILE_CLASS_RULES", ""),
+
+    MSISDNCountryCode:    getEnv("MSISDN_COUNTRY_CODE", ""),
+    MSISDNNationalLength: getEnv("MSISDN_NATIONAL_LENGTH", ""),
+    IMSIHomePLMNs:        getEnv("IMSI_HOME_PLMNS", ""),
+
+    ItemRetentionDays:   getEnv("ITEM_RETENTION_DAYS", "30"),
+    ReportRetentionDays: getEnv("REPORT_RETENTION_DAYS", "180"),
+    ExportRetentionDays: getEnv("EXPORT_RETENTION_DAYS", "7"),
+    RetentionInterval:   getEnv("RETENTION_INTERVAL", "1h"),
+
+    NumericValueTypes: getEnv("NUMERIC_VALUE_TYPES", "MSISDN,IMSI,ICCID,IMEI"),
   }
 }
 
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// Service runs inventory exports, either straight to a writer (the synchronous
+// endpoint) or into a GridFS file (export jobs)
+type Service struct {
+	db *mongo.Database
+}
+
+func NewService(db *mongo.Database) *Service {
+	return &Service{db: db}
+}
+
+// FileName is the GridFS / download name of an export job's file
+func FileName(jobID string) string {
+	return fmt.Sprintf("resource_export_%s.csv", jobID)
+}
+
+// Find opens the cursor of a query, sorted by value
+func (s *Service) Find(ctx context.Context, query model.ExportQuery) (*mongo.Cursor, error) {
+	coll := s.db.Collection(collectionName(query.BaseType))
+	return coll.Find(ctx, Filter(query), findOptions(query))
+}
+
+// WriteCSV writes the header and one row per document of cursor (the columns of
+// query.Fields) and returns the row count
+func (s *Service) WriteCSV(ctx context.Context, w io.Writer, cursor *mongo.Cursor, query model.ExportQuery) (int, error) {
+	fields := query.Fields
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return 0, fmt.Errorf("write header: %w", err)
+	}
+
+	rows := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return rows, fmt.Errorf("decode resource: %w", err)
+		}
+
+		row := make([]string, len(fields))
+		for i, f := range fields {
+			row[i] = ExtractField(doc, f, query.ArrayFormat)
+		}
+		if err := writer.Write(row); err != nil {
+			return rows, fmt.Errorf("write row: %w", err)
+		}
+		rows++
+	}
+	if err := cursor.Err(); err != nil {
+		return rows, fmt.Errorf("iterate resources: %w", err)
+	}
+
+	writer.Flush()
+	return rows, writer.Error()
+}
+
+// Store streams a query into a new GridFS file. On error the upload is aborted,
+// leaving no partial file
+func (s *Service) Store(ctx context.Context, name string, query model.ExportQuery) (primitive.ObjectID, int, error) {
+	cursor, err := s.Find(ctx, query)
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("query resources: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	bucket, err := gridfs.NewBucket(s.db)
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("failed to create GridFS bucket: %w", err)
+	}
+	stream, err := bucket.OpenUploadStream(name)
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("failed to open upload stream: %w", err)
+	}
+
+	rows, err := s.WriteCSV(ctx, stream, cursor, query)
+	if err != nil {
+		if abortErr := stream.Abort(); abortErr != nil {
+			log.Printf("Failed to abort GridFS upload %s: %v", name, abortErr)
+		}
+		return primitive.NilObjectID, rows, err
+	}
+	if err := stream.Close(); err != nil {
+		return primitive.NilObjectID, rows, fmt.Errorf("failed to close upload stream: %w", err)
+	}
+
+	fileID, ok := stream.FileID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, rows, fmt.Errorf("unexpected FileID type: %T", stream.FileID)
+	}
+	return fileID, rows, nil
+}
+
+// DeleteFile removes a stored export file; a file that is already gone is not an error
+func (s *Service) DeleteFile(ctx context.Context, fileID primitive.ObjectID) error {
+	bucket, err := gridfs.NewBucket(s.db)
+	if err != nil {
+		return fmt.Errorf("failed to create GridFS bucket: %w", err)
+	}
+	if err := bucket.DeleteContext(ctx, fileID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return fmt.Errorf("failed to delete GridFS file %s: %w", fileID.Hex(), err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Array formats for arrays of objects and embedded documents (arrayFormat param)
+const (
+	ArrayFormatJSON = "json" // JSON-encoded, e.g. [{"name":"ACME","role":"Customer"}]
+	ArrayFormatFlat = "flat" // flattened with indices, e.g. 0.name=ACME;0.role=Customer
+)
+
+// charPrefix selects a resourceCharacteristic value by code, e.g. char:MobileClass
+const charPrefix = "char:"
+
+// characteristicsField holds the characteristics of an inventory document
+const characteristicsField = "resourceCharacteristic"
+
+// ExtractField returns a string value for a given column from a Mongo document.
+// A column is a field name, a dot path into embedded documents and arrays
+// (cost.taxedValue, relatedParty.0.name, relatedParty.name) or char:<Code>.
+// Arrays of scalars are joined by ';'; arrays of objects and embedded documents
+// are rendered in arrayFormat. Secret characteristics (SIM PIN/PUK) are never exported
+func ExtractField(doc bson.M, field, arrayFormat string) string {
+	if code, ok := strings.CutPrefix(field, charPrefix); ok {
+		return characteristic(doc, code)
+	}
+	if strings.HasPrefix(field, characteristicsField) {
+		doc = withPublicCharacteristics(doc)
+	}
+
+	v, ok := doc[field]
+	if !ok {
+		v, ok = lookup(doc, strings.Split(field, "."))
+	}
+	if !ok {
+		return ""
+	}
+	return render(v, arrayFormat)
+}
+
+// lookup follows a dot path. A numeric segment indexes an array; any other segment
+// applied to an array collects it from every element
+func lookup(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+
+	switch t := v.(type) {
+	case bson.M:
+		child, ok := t[path[0]]
+		if !ok {
+			return nil, false
+		}
+		return lookup(child, path[1:])
+
+	case bson.D:
+		for _, e := range t {
+			if e.Key == path[0] {
+				return lookup(e.Value, path[1:])
+			}
+		}
+		return nil, false
+
+	case bson.A:
+		if idx, err := strconv.Atoi(path[0]); err == nil {
+			if idx < 0 || idx >= len(t) {
+				return nil, false
+			}
+			return lookup(t[idx], path[1:])
+		}
+		out := bson.A{}
+		for _, el := range t {
+			if x, ok := lookup(el, path); ok {
+				out = append(out, x)
+			}
+		}
+		return out, len(out) > 0
+	}
+	return nil, false
+}
+
+// characteristic returns the value of the resourceCharacteristic entry with the given code
+func characteristic(doc bson.M, code string) string {
+	if model.SecretCharacteristics[code] {
+		return ""
+	}
+	chars, _ := doc[characteristicsField].(bson.A)
+	for _, el := range chars {
+		c, ok := el.(bson.M)
+		if !ok {
+			continue
+		}
+		if c["code"] == code || (c["code"] == nil && c["name"] == code) {
+			return render(c["value"], ArrayFormatJSON)
+		}
+	}
+	return ""
+}
+
+// withPublicCharacteristics returns a shallow copy of doc without the secret
+// characteristics. The secret codes are matched rather than publicIdentifier,
+// which is left false on ordinary characteristics such as MobileClass
+func withPublicCharacteristics(doc bson.M) bson.M {
+	chars, ok := doc[characteristicsField].(bson.A)
+	if !ok {
+		return doc
+	}
+
+	public := bson.A{}
+	for _, el := range chars {
+		if c, ok := el.(bson.M); ok && secretCharacteristic(c) {
+			continue
+		}
+		public = append(public, el)
+	}
+
+	view := make(bson.M, len(doc))
+	for k, v := range doc {
+		view[k] = v
+	}
+	view[characteristicsField] = public
+	return view
+}
+
+func secretCharacteristic(c bson.M) bool {
+	code, _ := c["code"].(string)
+	name, _ := c["name"].(string)
+	return model.SecretCharacteristics[code] || model.SecretCharacteristics[name]
+}
+
+func render(v interface{}, arrayFormat string) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bson.A:
+		if scalars(val) {
+			// e.g. category: [], businessType: []
+			parts := make([]string, 0, len(val))
+			for _, el := range val {
+				parts = append(parts, render(el, arrayFormat))
+			}
+			return strings.Join(parts, ";")
+		}
+		return renderObject(val, arrayFormat)
+	case bson.M, bson.D:
+		return renderObject(val, arrayFormat)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func scalars(arr bson.A) bool {
+	for _, el := range arr {
+		switch el.(type) {
+		case bson.A, bson.M, bson.D:
+			return false
+		}
+	}
+	return true
+}
+
+func renderObject(v interface{}, arrayFormat string) string {
+	if arrayFormat == ArrayFormatFlat {
+		var parts []string
+		flatten("", v, &parts)
+		return strings.Join(parts, ";")
+	}
+
+	b, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// flatten writes one path=value entry per scalar, e.g. 0.name=ACME
+func flatten(prefix string, v interface{}, parts *[]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch t := v.(type) {
+	case bson.M:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(join(k), t[k], parts)
+		}
+	case bson.D:
+		for _, e := range t {
+			flatten(join(e.Key), e.Value, parts)
+		}
+	case bson.A:
+		for i, el := range t {
+			flatten(join(strconv.Itoa(i)), el, parts)
+		}
+	default:
+		*parts = append(*parts, prefix+"="+render(v, ArrayFormatFlat))
+	}
+}
+
+// jsonValue converts decoded BSON into values encoding/json renders naturally
+// (ordered documents as objects, dates as RFC3339, ObjectIDs as hex)
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		out := make(map[string]interface{}, len(t))
+		for k, x := range t {
+			out[k] = jsonValue(x)
+		}
+		return out
+	case bson.D:
+		out := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			out[e.Key] = jsonValue(e.Value)
+		}
+		return out
+	case bson.A:
+		out := make([]interface{}, len(t))
+		for i, x := range t {
+			out[i] = jsonValue(x)
+		}
+		return out
+	case primitive.DateTime:
+		return t.Time().UTC()
+	case primitive.ObjectID:
+		return t.Hex()
+	}
+	return v
+}
@@ -0,0 +1,206 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ParseFilter reads the filter query params:
+//
+//	category       = category membership, comma separated or repeated (any of)
+//	businessType   = businessType membership, comma separated or repeated (any of)
+//	createdFrom / createdTo, updatedFrom / updatedTo = date range (RFC3339 or 2006-01-02)
+//	relatedParty   = relatedParty.name
+//	char           = characteristic Code:Value, comma separated or repeated, e.g. MobileClass:Gold
+//	match          = all | any  (default: all) combines the conditions above
+//	filter         = JSON ExportFilter, ANDed with the conditions above
+//
+// It returns nil when no filter param is set
+func ParseFilter(q url.Values) (*model.ExportFilter, error) {
+	var conds []model.ExportFilter
+
+	if v := listParam(q, "category"); len(v) > 0 {
+		conds = append(conds, model.ExportFilter{Category: v})
+	}
+	if v := listParam(q, "businessType"); len(v) > 0 {
+		conds = append(conds, model.ExportFilter{BusinessType: v})
+	}
+
+	// each range is one condition holding both bounds, so match=any cannot OR them apart
+	for _, p := range []struct {
+		field string
+		set   func(f *model.ExportFilter, from, to *time.Time)
+	}{
+		{"created", func(f *model.ExportFilter, from, to *time.Time) { f.CreatedFrom, f.CreatedTo = from, to }},
+		{"updated", func(f *model.ExportFilter, from, to *time.Time) { f.UpdatedFrom, f.UpdatedTo = from, to }},
+	} {
+		from, err := dateParam(q, p.field+"From", false)
+		if err != nil {
+			return nil, err
+		}
+		to, err := dateParam(q, p.field+"To", true)
+		if err != nil {
+			return nil, err
+		}
+		if from == nil && to == nil {
+			continue
+		}
+		var cond model.ExportFilter
+		p.set(&cond, from, to)
+		conds = append(conds, cond)
+	}
+
+	if v := q.Get("relatedParty"); v != "" {
+		conds = append(conds, model.ExportFilter{RelatedPartyName: v})
+	}
+
+	for _, pair := range listParam(q, "char") {
+		code, value, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(code) == "" {
+			return nil, fmt.Errorf("invalid char %q, expected Code:Value", pair)
+		}
+		conds = append(conds, model.ExportFilter{Characteristics: []model.CharacteristicMatch{
+			{Code: strings.TrimSpace(code), Value: strings.TrimSpace(value)},
+		}})
+	}
+
+	var filter model.ExportFilter
+	switch match := q.Get("match"); match {
+	case "", "all":
+		filter.And = conds
+	case "any":
+		filter.Or = conds
+	default:
+		return nil, fmt.Errorf("invalid match %q (all, any)", match)
+	}
+
+	if v := q.Get("filter"); v != "" {
+		var body model.ExportFilter
+		if err := json.Unmarshal([]byte(v), &body); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		filter.And = append(filter.And, body)
+	}
+
+	if len(filter.And) == 0 && len(filter.Or) == 0 {
+		return nil, nil
+	}
+	return &filter, nil
+}
+
+// listParam splits a repeated and/or comma separated param
+func listParam(q url.Values, name string) []string {
+	var out []string
+	for _, raw := range q[name] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// dateParam parses an optional date bound param; nil when it is not set
+func dateParam(q url.Values, name string, upper bool) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := parseDate(v, upper)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	return &t, nil
+}
+
+// parseDate accepts RFC3339 timestamps and plain dates. A plain date as upper bound
+// covers the whole day
+func parseDate(v string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Millisecond)
+	}
+	return t, nil
+}
+
+// filterExpr translates a filter tree into a Mongo query document
+func filterExpr(f model.ExportFilter) bson.M {
+	var parts bson.A
+
+	if f.Type != "" {
+		parts = append(parts, bson.M{"type": f.Type})
+	}
+	if f.ResourceStatus != "" {
+		parts = append(parts, bson.M{"resourceStatus": f.ResourceStatus})
+	}
+	if len(f.Category) > 0 {
+		parts = append(parts, bson.M{"category": bson.M{"$in": f.Category}})
+	}
+	if len(f.BusinessType) > 0 {
+		parts = append(parts, bson.M{"businessType": bson.M{"$in": f.BusinessType}})
+	}
+	if r := dateRange(f.CreatedFrom, f.CreatedTo); r != nil {
+		parts = append(parts, bson.M{"createdAt": r})
+	}
+	if r := dateRange(f.UpdatedFrom, f.UpdatedTo); r != nil {
+		parts = append(parts, bson.M{"updatedAt": r})
+	}
+	if f.RelatedPartyName != "" {
+		parts = append(parts, bson.M{"relatedParty.name": f.RelatedPartyName})
+	}
+	for _, c := range f.Characteristics {
+		parts = append(parts, bson.M{"resourceCharacteristic": bson.M{
+			"$elemMatch": bson.M{"code": c.Code, "value": c.Value},
+		}})
+	}
+
+	for _, child := range f.And {
+		if expr := filterExpr(child); len(expr) > 0 {
+			parts = append(parts, expr)
+		}
+	}
+	if len(f.Or) > 0 {
+		alts := bson.A{}
+		for _, child := range f.Or {
+			// an empty alternative matches everything
+			alts = append(alts, filterExpr(child))
+		}
+		parts = append(parts, bson.M{"$or": alts})
+	}
+
+	switch len(parts) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return parts[0].(bson.M)
+	}
+	return bson.M{"$and": parts}
+}
+
+func dateRange(from, to *time.Time) bson.M {
+	if from == nil && to == nil {
+		return nil
+	}
+	r := bson.M{}
+	if from != nil {
+		r["$gte"] = *from
+	}
+	if to != nil {
+		r["$lte"] = *to
+	}
+	return r
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultSyncLimit caps the synchronous export when no limit is given
+const DefaultSyncLimit = 1000
+
+// ParseQuery reads the export query params:
+//
+//	baseType        = LogicalResource | PhysicalResource  (default: LogicalResource)
+//	limit           = number of rows to export (default: defaultLimit, 0 = no limit)
+//	fields          = comma-separated list of columns, "value" is always included:
+//	                  field names, dot paths (cost.taxedValue) or char:<Code> (char:MobileClass)
+//	arrayFormat     = json | flat  rendering of arrays of objects and embedded documents (default: json)
+//	type            = filter by resource type
+//	resourceStatus  = filter by resourceStatus
+//	valueFrom       = lower bound for value (inclusive)
+//	valueTo         = upper bound for value (inclusive)
+//	numericOrder    = true | false  (default: numeric for the types in numeric, see NumericValueTypes.Ordered)
+//
+// plus the filter params of ParseFilter
+func ParseQuery(q url.Values, defaultLimit int64, numeric model.NumericValueTypes) (model.ExportQuery, error) {
+	query := model.ExportQuery{
+		BaseType:       q.Get("baseType"),
+		Type:           q.Get("type"),
+		ResourceStatus: q.Get("resourceStatus"),
+		ValueFrom:      q.Get("valueFrom"),
+		ValueTo:        q.Get("valueTo"),
+		Limit:          defaultLimit,
+	}
+	if query.BaseType != "PhysicalResource" {
+		query.BaseType = "LogicalResource"
+	}
+
+	switch query.ArrayFormat = q.Get("arrayFormat"); query.ArrayFormat {
+	case "":
+		query.ArrayFormat = ArrayFormatJSON
+	case ArrayFormatJSON, ArrayFormatFlat:
+	default:
+		return model.ExportQuery{}, fmt.Errorf("invalid arrayFormat %q (json, flat)", query.ArrayFormat)
+	}
+
+	query.NumericOrder = numeric.Ordered(query.Type, query.ValueFrom, query.ValueTo)
+	if v := q.Get("numericOrder"); v != "" {
+		ordered, err := strconv.ParseBool(v)
+		if err != nil {
+			return model.ExportQuery{}, fmt.Errorf("invalid numericOrder %q", v)
+		}
+		query.NumericOrder = ordered
+	}
+
+	if lStr := q.Get("limit"); lStr != "" {
+		if l, err := strconv.ParseInt(lStr, 10, 64); err == nil && l > 0 {
+			query.Limit = l
+		}
+	}
+
+	// Fields (dynamic header). "value" must always be present
+	fieldsParam := q.Get("fields")
+	if fieldsParam == "" {
+		fieldsParam = "value" // default only value
+	}
+	hasValue := false
+	for _, f := range strings.Split(fieldsParam, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if f == "value" {
+			hasValue = true
+		}
+		query.Fields = append(query.Fields, f)
+	}
+	if !hasValue {
+		query.Fields = append([]string{"value"}, query.Fields...)
+	}
+
+	filter, err := ParseFilter(q)
+	if err != nil {
+		return model.ExportQuery{}, err
+	}
+	query.Filter = filter
+	return query, nil
+}
+
+// collectionName is the inventory collection of a baseType
+func collectionName(baseType string) string {
+	if baseType == "PhysicalResource" {
+		return "physicalresources"
+	}
+	return "logicalresources"
+}
+
+// Filter builds the Mongo filter of a query
+func Filter(query model.ExportQuery) bson.M {
+	filter := bson.M{"baseType": query.BaseType}
+
+	if query.Type != "" {
+		filter["type"] = query.Type
+	}
+	if query.ResourceStatus != "" {
+		filter["resourceStatus"] = query.ResourceStatus
+	}
+
+	// Optional range on value
+	if query.ValueFrom != "" || query.ValueTo != "" {
+		rangeCond := bson.M{}
+		if query.ValueFrom != "" {
+			rangeCond["$gte"] = query.ValueFrom
+		}
+		if query.ValueTo != "" {
+			rangeCond["$lte"] = query.ValueTo
+		}
+		filter["value"] = rangeCond
+	}
+
+	if query.Filter != nil {
+		if expr := filterExpr(*query.Filter); len(expr) > 0 {
+			filter["$and"] = bson.A{expr}
+		}
+	}
+	return filter
+}
+
+// findOptions sorts by value and applies the limit. Numeric queries use the numeric
+// collation, which applies to the value range and the sort alike. No index covers
+// every filter combination, so a large sort is allowed to spill to disk
+func findOptions(query model.ExportQuery) *options.FindOptions {
+	opts := options.Find().SetSort(bson.D{{Key: "value", Value: 1}}).SetAllowDiskUse(true)
+	if query.NumericOrder {
+		opts.SetCollation(repository.NumericCollation)
+	}
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+	return opts
+}
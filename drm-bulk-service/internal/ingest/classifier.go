@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+)
+
+// DefaultClassRules ranks MSISDNs by how memorable they are. Classes are tried in
+// order; a class applies when any of its conditions holds:
+//
+//	repeat>=N    N identical digits in a row (e.g. 7777)
+//	sequence>=N  N ascending or descending consecutive digits (e.g. 12345)
+//	mirror>=N    the last N digits read the same backwards (e.g. 123321)
+const DefaultClassRules = "Platinum:repeat>=6,sequence>=7,mirror>=8;" +
+	"Gold:repeat>=5,sequence>=6,mirror>=6;" +
+	"Silver:repeat>=4,sequence>=5,mirror>=4"
+
+// DefaultClass is used when no class rule matches
+const DefaultClass = "Normal"
+
+// Classify modes of a create request
+const (
+	ClassifyOverride = "override" // always write the computed MobileClass
+	ClassifyValidate = "validate" // reject rows whose MobileClass differs from the computed one
+)
+
+type classCondition struct {
+	kind string // repeat | sequence | mirror
+	min  int
+}
+
+type classRule struct {
+	class      string
+	conditions []classCondition
+}
+
+// Classifier computes the MobileClass of an MSISDN from pattern rules
+type Classifier struct {
+	rules []classRule
+}
+
+// ParseClassRules builds a Classifier from a spec such as DefaultClassRules;
+// an empty spec selects the defaults
+func ParseClassRules(spec string) (*Classifier, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultClassRules
+	}
+
+	c := &Classifier{}
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		class, conds, ok := strings.Cut(part, ":")
+		class = strings.TrimSpace(class)
+		if !ok || class == "" {
+			return nil, fmt.Errorf("invalid class rule %q: want Class:condition,...", part)
+		}
+
+		rule := classRule{class: class}
+		for _, cond := range strings.Split(conds, ",") {
+			kind, n, ok := strings.Cut(strings.TrimSpace(cond), ">=")
+			if !ok {
+				return nil, fmt.Errorf("invalid condition %q in class %s", cond, class)
+			}
+			min, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil || min < 2 {
+				return nil, fmt.Errorf("invalid length in condition %q of class %s", cond, class)
+			}
+			switch kind = strings.TrimSpace(kind); kind {
+			case "repeat", "sequence", "mirror":
+			default:
+				return nil, fmt.Errorf("unknown condition %q in class %s", kind, class)
+			}
+			rule.conditions = append(rule.conditions, classCondition{kind: kind, min: min})
+		}
+		c.rules = append(c.rules, rule)
+	}
+	return c, nil
+}
+
+// Class returns the first class whose rules match msisdn, or DefaultClass
+func (c *Classifier) Class(msisdn string) string {
+	digits := onlyDigits(msisdn)
+	for _, rule := range c.rules {
+		for _, cond := range rule.conditions {
+			if cond.matches(digits) {
+				return rule.class
+			}
+		}
+	}
+	return DefaultClass
+}
+
+func (cond classCondition) matches(digits string) bool {
+	switch cond.kind {
+	case "repeat":
+		return longestRun(digits, 0) >= cond.min
+	case "sequence":
+		return longestRun(digits, 1) >= cond.min || longestRun(digits, -1) >= cond.min
+	case "mirror":
+		if len(digits) < cond.min {
+			return false
+		}
+		tail := digits[len(digits)-cond.min:]
+		for i, j := 0, len(tail)-1; i < j; i, j = i+1, j-1 {
+			if tail[i] != tail[j] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// longestRun returns the longest run of digits where each digit is the previous one plus step
+func longestRun(digits string, step int) int {
+	if digits == "" {
+		return 0
+	}
+	best, run := 1, 1
+	for i := 1; i < len(digits); i++ {
+		if int(digits[i])-int(digits[i-1]) == step {
+			run++
+			if run > best {
+				best = run
+			}
+		} else {
+			run = 1
+		}
+	}
+	return best
+}
+
+func onlyDigits(v string) string {
+	var b strings.Builder
+	for _, c := range v {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// ClassifyStage writes the computed MobileClass of each MSISDN row (override),
+// or rejects rows whose supplied MobileClass differs from it (validate)
+type ClassifyStage struct {
+	classifier *Classifier
+	mode       string
+}
+
+func NewClassifyStage(classifier *Classifier, mode string) (*ClassifyStage, error) {
+	if mode != ClassifyOverride && mode != ClassifyValidate {
+		return nil, fmt.Errorf("invalid classify mode %q: want %s or %s", mode, ClassifyOverride, ClassifyValidate)
+	}
+	return &ClassifyStage{classifier: classifier, mode: mode}, nil
+}
+
+func (s *ClassifyStage) Name() string { return "classify" }
+
+func (s *ClassifyStage) Apply(item *model.BulkItem) error {
+	msisdn, ok := characteristic(item, "MSISDN")
+	if !ok || msisdn == "" {
+		return nil
+	}
+
+	computed := s.classifier.Class(msisdn)
+	supplied, _ := characteristic(item, "MobileClass")
+	if s.mode == ClassifyValidate && supplied != "" && !strings.EqualFold(supplied, computed) {
+		return newError(CodeMobileClassMismatch, "MobileClass %s does not match computed class %s", supplied, computed)
+	}
+	setCharacteristic(item, "MobileClass", computed)
+	return nil
+}
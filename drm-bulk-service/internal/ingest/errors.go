@@ -0,0 +1,35 @@
+package ingest
+
+import "fmt"
+
+// Error codes of rows rejected at ingest
+const (
+	CodeMSISDNInvalid   = "MSISDN_INVALID"
+	CodeICCIDLength     = "ICCID_LENGTH"
+	CodeICCIDPrefix     = "ICCID_PREFIX"
+	CodeICCIDCheckDigit = "ICCID_CHECK_DIGIT"
+	CodeIMEILength      = "IMEI_LENGTH"
+	CodeIMEICheckDigit  = "IMEI_CHECK_DIGIT"
+	CodeIMSILength      = "IMSI_LENGTH"
+	CodeIMSIMCC         = "IMSI_MCC"
+	CodeIMSIPLMN        = "IMSI_PLMN"
+	CodeNotNumeric      = "NOT_NUMERIC"
+
+	CodeMobileClassMismatch = "MOBILE_CLASS_MISMATCH"
+	CodeRuleViolation       = "RULE_VIOLATION"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+)
+
+// Error is a row rejection with a stable code the report can be filtered on
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,254 @@
+package ingest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+)
+
+// Normalizer canonicalizes identifiers keyed by ResourceCharacteristic.Code
+// and validates their structure:
+//
+//	MSISDN  digits only in E.164 form (country code + national number, no "+")
+//	ICCID   19-20 digits, "89" prefix, Luhn check digit
+//	IMEI    15 digits, Luhn check digit
+//	IMSI    14-15 digits, MCC 200-799, optionally one of the home PLMNs (MCC+MNC)
+type Normalizer struct {
+	countryCode    string   // e.g. 98; empty keeps MSISDNs as international numbers only
+	nationalLength int      // national significant number length, e.g. 10; 0 when unknown
+	homePLMNs      []string // e.g. 43211, 43235; empty accepts any MCC/MNC
+}
+
+// NewNormalizer validates the MSISDN country code, the national significant number
+// length (empty when unknown) and the IMSI home PLMN list (comma separated)
+func NewNormalizer(countryCode, nationalLength, homePLMNs string) (*Normalizer, error) {
+	n := &Normalizer{countryCode: strings.TrimPrefix(strings.TrimSpace(countryCode), "+")}
+	if n.countryCode != "" && (len(n.countryCode) > 3 || !isDigits(n.countryCode)) {
+		return nil, fmt.Errorf("invalid country code %q", countryCode)
+	}
+
+	if nationalLength = strings.TrimSpace(nationalLength); nationalLength != "" {
+		l, err := strconv.Atoi(nationalLength)
+		if err != nil || l < 4 || len(n.countryCode)+l > 15 {
+			return nil, fmt.Errorf("invalid national number length %q", nationalLength)
+		}
+		n.nationalLength = l
+	}
+
+	for _, plmn := range strings.Split(homePLMNs, ",") {
+		plmn = strings.TrimSpace(plmn)
+		if plmn == "" {
+			continue
+		}
+		if (len(plmn) != 5 && len(plmn) != 6) || !isDigits(plmn) {
+			return nil, fmt.Errorf("invalid PLMN %q: want MCC+MNC (5 or 6 digits)", plmn)
+		}
+		n.homePLMNs = append(n.homePLMNs, plmn)
+	}
+	return n, nil
+}
+
+// Normalize canonicalizes value according to code; unknown codes are returned unchanged
+func (n *Normalizer) Normalize(code, value string) (string, error) {
+	switch code {
+	case "MSISDN":
+		return n.msisdn(value)
+	case "ICCID":
+		return iccid(value)
+	case "IMEI":
+		return imei(value)
+	case "IMSI":
+		return n.imsi(value)
+	default:
+		return value, nil
+	}
+}
+
+func (n *Normalizer) msisdn(value string) (string, error) {
+	v := stripFormatting(value)
+
+	international := false
+	switch {
+	case strings.HasPrefix(v, "+"):
+		v, international = v[1:], true
+	case strings.HasPrefix(v, "00"):
+		v, international = v[2:], true
+	}
+	if !isDigits(v) {
+		return "", newError(CodeMSISDNInvalid, "MSISDN %q contains non-digit characters", value)
+	}
+
+	// a national number is recognized by its trunk 0 or, when the national length
+	// is known, by having exactly that many digits; anything else is taken as
+	// international already, even if it happens to start with the country code
+	if !international && n.countryCode != "" {
+		switch {
+		case strings.HasPrefix(v, "0"):
+			v = n.countryCode + v[1:] // national trunk prefix
+		case n.nationalLength > 0 && len(v) == n.nationalLength:
+			v = n.countryCode + v
+		}
+	}
+
+	if len(v) < 8 || len(v) > 15 {
+		return "", newError(CodeMSISDNInvalid, "MSISDN %q is not a valid E.164 number", value)
+	}
+	return v, nil
+}
+
+func iccid(value string) (string, error) {
+	v := stripFormatting(value)
+	if !isDigits(v) {
+		return "", newError(CodeNotNumeric, "ICCID %q contains non-digit characters", value)
+	}
+	if len(v) != 19 && len(v) != 20 {
+		return "", newError(CodeICCIDLength, "ICCID %q must have 19 or 20 digits", value)
+	}
+	if !strings.HasPrefix(v, "89") {
+		return "", newError(CodeICCIDPrefix, "ICCID %q must start with 89", value)
+	}
+	if !luhnValid(v) {
+		return "", newError(CodeICCIDCheckDigit, "ICCID %q has an invalid check digit", value)
+	}
+	return v, nil
+}
+
+func imei(value string) (string, error) {
+	v := stripFormatting(value)
+	if !isDigits(v) {
+		return "", newError(CodeNotNumeric, "IMEI %q contains non-digit characters", value)
+	}
+	if len(v) != 15 {
+		return "", newError(CodeIMEILength, "IMEI %q must have 15 digits", value)
+	}
+	if !luhnValid(v) {
+		return "", newError(CodeIMEICheckDigit, "IMEI %q has an invalid check digit", value)
+	}
+	return v, nil
+}
+
+func (n *Normalizer) imsi(value string) (string, error) {
+	v := stripFormatting(value)
+	if !isDigits(v) {
+		return "", newError(CodeNotNumeric, "IMSI %q contains non-digit characters", value)
+	}
+	if len(v) < 14 || len(v) > 15 {
+		return "", newError(CodeIMSILength, "IMSI %q must have 14 or 15 digits", value)
+	}
+	if v[0] < '2' || v[0] > '7' {
+		return "", newError(CodeIMSIMCC, "IMSI %q has an invalid MCC %s", value, v[:3])
+	}
+	if len(n.homePLMNs) > 0 {
+		for _, plmn := range n.homePLMNs {
+			if strings.HasPrefix(v, plmn) {
+				return v, nil
+			}
+		}
+		return "", newError(CodeIMSIPLMN, "IMSI %q does not belong to a home PLMN", value)
+	}
+	return v, nil
+}
+
+// luhnValid checks the trailing Luhn check digit of a digit string
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// stripFormatting removes the separators people type into identifiers
+func stripFormatting(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(v))
+}
+
+func isDigits(v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, c := range v {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeStage canonicalizes every known identifier characteristic of a row.
+// When the item value is one of those identifiers it is rewritten too, so the
+// resource is created under its canonical value
+type NormalizeStage struct {
+	normalizer *Normalizer
+}
+
+func NewNormalizeStage(normalizer *Normalizer) *NormalizeStage {
+	return &NormalizeStage{normalizer: normalizer}
+}
+
+func (s *NormalizeStage) Name() string { return "normalize" }
+
+func (s *NormalizeStage) Apply(item *model.BulkItem) error {
+	for i, rc := range item.ResourceCharacteristic {
+		if rc.Value == "" {
+			continue
+		}
+		v, err := s.normalizer.Normalize(rc.Code, rc.Value)
+		if err != nil {
+			return err
+		}
+		if item.Value == rc.Value {
+			item.Value = v
+		}
+		item.ResourceCharacteristic[i].Value = v
+	}
+	return nil
+}
+
+// LookupStage canonicalizes the identifiers an operation looks existing resources
+// up by: the item value, and a link's target value, normalized according to their
+// resource type. A retire file listing 0912... then finds the MSISDN that was
+// created as 98912...; a value that cannot be normalized rejects the row
+type LookupStage struct {
+	normalizer *Normalizer
+}
+
+func NewLookupStage(normalizer *Normalizer) *LookupStage {
+	return &LookupStage{normalizer: normalizer}
+}
+
+func (s *LookupStage) Name() string { return "normalize" }
+
+func (s *LookupStage) Apply(item *model.BulkItem) error {
+	v, err := s.normalizer.Normalize(item.Type, item.Value)
+	if err != nil {
+		return err
+	}
+	item.Value = v
+
+	if item.Link != nil && item.Link.TargetValue != "" {
+		v, err := s.normalizer.Normalize(item.Link.TargetType, item.Link.TargetValue)
+		if err != nil {
+			return err
+		}
+		item.Link.TargetValue = v
+	}
+	return nil
+}
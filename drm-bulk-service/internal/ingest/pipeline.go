@@ -0,0 +1,76 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+
+	"drm-bulk-service/internal/model"
+)
+
+// Stage inspects or rewrites one BulkItem before it is stored.
+// Returning an error rejects the item; it is saved with status "failure"
+// and never sent to inventory
+type Stage interface {
+	Name() string
+	Apply(item *model.BulkItem) error
+}
+
+// Pipeline runs the ingest stages of a bulk request over its items, in order.
+// The first stage that rejects an item stops the pipeline for that item
+type Pipeline struct {
+	stages []Stage
+}
+
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Add appends a stage to the pipeline
+func (p *Pipeline) Add(stage Stage) {
+	p.stages = append(p.stages, stage)
+}
+
+// Run applies all stages to every item and returns how many items were rejected
+func (p *Pipeline) Run(items []model.BulkItem) int {
+	rejected := 0
+	for i := range items {
+		for _, stage := range p.stages {
+			if err := stage.Apply(&items[i]); err != nil {
+				code := CodeValidationFailed
+				var ie *Error
+				if errors.As(err, &ie) {
+					code = ie.Code
+				}
+
+				items[i].Status = "failure"
+				items[i].ErrorMessage = fmt.Sprintf("%s: %v", stage.Name(), err)
+				items[i].ErrorCode = code
+				items[i].ErrorCategory = model.ErrorCategoryValidation
+				rejected++
+				break
+			}
+		}
+	}
+	return rejected
+}
+
+// characteristic returns the value of the characteristic with the given code
+func characteristic(item *model.BulkItem, code string) (string, bool) {
+	for _, rc := range item.ResourceCharacteristic {
+		if rc.Code == code {
+			return rc.Value, true
+		}
+	}
+	return "", false
+}
+
+// setCharacteristic replaces or appends the characteristic with the given code
+func setCharacteristic(item *model.BulkItem, code, value string) {
+	for i, rc := range item.ResourceCharacteristic {
+		if rc.Code == code {
+			item.ResourceCharacteristic[i].Value = value
+			return
+		}
+	}
+	item.ResourceCharacteristic = append(item.ResourceCharacteristic, model.ResourceCharacteristic{Code: code, Value: value})
+}
@@ -0,0 +1,600 @@
+package ingest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"drm-bulk-service/internal/model"
+)
+
+/*
+===========================
+Validation rules DSL
+
+Rules are stored on the resourceschemas document ("rules": [{name, expr}])
+and evaluated per row, in order. A row that does not satisfy a rule is
+rejected with the rule name in its error message.
+
+	expr        := "if" or "then" or | or
+	or          := and ("or" and)*
+	and         := not ("and" not)*
+	not         := "not" not | primary
+	primary     := "(" expr ")" | call | operand [op operand | "in" list]
+	call        := unique(field, ...) | required(field, ...)
+	op          := == | != | < | <= | > | >=
+	list        := "(" literal ("," literal)* ")"
+	operand     := field | "string" | number
+
+Fields resolve to the row's characteristics (by code), update fields,
+or value / type / baseType; a field the row does not have is a rule
+error. The request's category and businessType are not row fields and
+cannot be referenced. Ordering comparisons fail when a side is empty; dates (RFC3339
+or 2006-01-02) and numbers compare by value, anything else as text.
+unique() is checked across all rows of the file and ignores rows whose
+fields are all empty; a row only takes its unique() keys when it passes
+every rule, so a rejected row never makes a later row a duplicate; unique() and required() treat a missing field as
+empty.
+
+Examples:
+
+	unique(IMSI, MSISDN)
+	endOperatingDate > startOperatingDate
+	if type == "MSISDN" then MobileClass in ("Platinum", "Gold", "Silver")
+
+===========================
+*/
+
+// RuleSet is a parsed list of named rules
+type RuleSet struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	name string
+	root node
+}
+
+// ParseRules compiles the rules of a schema
+func ParseRules(rules []model.ValidationRule) (*RuleSet, error) {
+	rs := &RuleSet{}
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule without name: %q", r.Expr)
+		}
+		root, err := parseExpr(r.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		rs.rules = append(rs.rules, compiledRule{name: r.Name, root: root})
+	}
+	return rs, nil
+}
+
+// RuleStage evaluates a RuleSet on every row. It keeps the state of the
+// unique() calls, so one stage must be used for exactly one file
+type RuleStage struct {
+	rules *RuleSet
+	seen  map[string]bool // "<rule>\x00<call>\x00<key>" of unique() tuples already used
+}
+
+func NewRuleStage(rules *RuleSet) *RuleStage {
+	return &RuleStage{rules: rules, seen: map[string]bool{}}
+}
+
+func (s *RuleStage) Name() string { return "rules" }
+
+func (s *RuleStage) Apply(item *model.BulkItem) error {
+	var keys []string
+	for _, rule := range s.rules.rules {
+		env := &evalEnv{item: item, stage: s, rule: rule.name}
+		ok, err := rule.root.eval(env)
+		keys = append(keys, env.keys...)
+		if err != nil {
+			return newError(CodeRuleViolation, "rule %s: %v", rule.name, err)
+		}
+		if !ok {
+			if env.detail != "" {
+				return newError(CodeRuleViolation, "rule %s failed: %s", rule.name, env.detail)
+			}
+			return newError(CodeRuleViolation, "rule %s failed", rule.name)
+		}
+	}
+
+	for _, key := range keys {
+		s.seen[key] = true
+	}
+	return nil
+}
+
+/*
+===========================
+Evaluation
+===========================
+*/
+
+type evalEnv struct {
+	item   *model.BulkItem
+	stage  *RuleStage
+	rule   string
+	detail string   // why unique() / required() failed
+	keys   []string // unique() keys of this row, committed once the row passes
+}
+
+// field resolves a field name on the row; ok is false when the row has no such field
+func (e *evalEnv) field(name string) (string, bool) {
+	switch name {
+	case "value":
+		return e.item.Value, true
+	case "type":
+		return e.item.Type, true
+	case "baseType":
+		return e.item.BaseType, true
+	}
+	if v, ok := characteristic(e.item, name); ok {
+		return v, true
+	}
+	v, ok := e.item.UpdateFields[name]
+	return v, ok
+}
+
+// optionalField resolves a field name, treating a missing field as empty
+func (e *evalEnv) optionalField(name string) string {
+	v, _ := e.field(name)
+	return v
+}
+
+type node interface {
+	eval(env *evalEnv) (bool, error)
+}
+
+type ifNode struct{ cond, then node }
+
+func (n ifNode) eval(env *evalEnv) (bool, error) {
+	ok, err := n.cond.eval(env)
+	if err != nil || !ok {
+		return true, err
+	}
+	return n.then.eval(env)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(env *evalEnv) (bool, error) {
+	ok, err := n.left.eval(env)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.eval(env)
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(env *evalEnv) (bool, error) {
+	ok, err := n.left.eval(env)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return n.right.eval(env)
+}
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(env *evalEnv) (bool, error) {
+	ok, err := n.inner.eval(env)
+	return !ok, err
+}
+
+type operand struct {
+	field   string // set for field references
+	literal string
+}
+
+func (o operand) value(env *evalEnv) (string, error) {
+	if o.field == "" {
+		return o.literal, nil
+	}
+	v, ok := env.field(o.field)
+	if !ok {
+		return "", fmt.Errorf("unknown field %s", o.field)
+	}
+	return v, nil
+}
+
+// String names the operand in failure details
+func (o operand) String() string {
+	if o.field != "" {
+		return o.field
+	}
+	return strconv.Quote(o.literal)
+}
+
+type compareNode struct {
+	left, right operand
+	op          string
+}
+
+func (n compareNode) eval(env *evalEnv) (bool, error) {
+	l, err := n.left.value(env)
+	if err != nil {
+		return false, err
+	}
+	r, err := n.right.value(env)
+	if err != nil {
+		return false, err
+	}
+	switch n.op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+
+	if l == "" || r == "" {
+		env.detail = fmt.Sprintf("%s %s %s: empty value", n.left, n.op, n.right)
+		return false, nil
+	}
+	c := compareValues(l, r)
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", n.op)
+}
+
+// compareValues orders two values as dates, then numbers, then text
+func compareValues(l, r string) int {
+	if lt, err := parseRuleTime(l); err == nil {
+		if rt, err := parseRuleTime(r); err == nil {
+			return lt.Compare(rt)
+		}
+	}
+	if lf, err := strconv.ParseFloat(l, 64); err == nil {
+		if rf, err := strconv.ParseFloat(r, 64); err == nil {
+			switch {
+			case lf < rf:
+				return -1
+			case lf > rf:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(l, r)
+}
+
+func parseRuleTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+type inNode struct {
+	left operand
+	list []string
+}
+
+func (n inNode) eval(env *evalEnv) (bool, error) {
+	v, err := n.left.value(env)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range n.list {
+		if v == item {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type uniqueNode struct {
+	id     string // position of the call in the expression, keeps two unique() calls apart
+	fields []string
+}
+
+func (n uniqueNode) eval(env *evalEnv) (bool, error) {
+	values := make([]string, len(n.fields))
+	blank := true
+	for i, f := range n.fields {
+		values[i] = env.optionalField(f)
+		blank = blank && values[i] == ""
+	}
+	if blank {
+		// rows without any of the fields are not duplicates of each other
+		return true, nil
+	}
+	key := env.rule + "\x00" + n.id + "\x00" + strings.Join(values, "\x00")
+	if env.stage.seen[key] {
+		env.detail = fmt.Sprintf("duplicate %s %s", strings.Join(n.fields, ","), strings.Join(values, ","))
+		return false, nil
+	}
+	env.keys = append(env.keys, key)
+	return true, nil
+}
+
+type requiredNode struct{ fields []string }
+
+func (n requiredNode) eval(env *evalEnv) (bool, error) {
+	for _, f := range n.fields {
+		if env.optionalField(f) == "" {
+			env.detail = f + " is required"
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+/*
+===========================
+Parsing
+===========================
+*/
+
+type token struct {
+	kind string // ident | string | number | op | punct | eof
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], byte(c))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{kind: "string", text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c == '(' || c == ')' || c == ',':
+			toks = append(toks, token{kind: "punct", text: string(c), pos: i})
+			i++
+		case strings.ContainsRune("=!<>", c):
+			op := string(c)
+			if i+1 < len(src) && src[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("unknown operator %q at %d", op, i)
+			}
+			toks = append(toks, token{kind: "op", text: op, pos: i})
+			i += len(op)
+		case unicode.IsDigit(c) || c == '-':
+			j := i + 1
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: "number", text: src[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || strings.IndexByte("_.", src[j]) >= 0) {
+				j++
+			}
+			toks = append(toks, token{kind: "ident", text: src[i:j], pos: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return append(toks, token{kind: "eof", pos: len(src)}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func parseExpr(src string) (node, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+
+	var root node
+	if p.keyword("if") {
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("then") {
+			return nil, p.errorf("expected then")
+		}
+		then, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		root = ifNode{cond: cond, then: then}
+	} else if root, err = p.or(); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != "eof" {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at %d: %s", p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+// keyword consumes the identifier kw when it is next
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == "ident" && t.text == kw {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == "punct" && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.keyword("not") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner: inner}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	if p.punct("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, p.errorf("expected )")
+		}
+		return inner, nil
+	}
+
+	t := p.peek()
+	if t.kind == "ident" && (t.text == "unique" || t.text == "required") {
+		p.next()
+		fields, err := p.fieldList()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "unique" {
+			return uniqueNode{id: strconv.Itoa(t.pos), fields: fields}, nil
+		}
+		return requiredNode{fields: fields}, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("in") {
+		list, err := p.literalList()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{left: left, list: list}, nil
+	}
+	if p.peek().kind != "op" {
+		return nil, p.errorf("expected comparison after %q", t.text)
+	}
+	op := p.next().text
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{left: left, right: right, op: op}, nil
+}
+
+func (p *parser) operand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case "ident":
+		return operand{field: t.text}, nil
+	case "string", "number":
+		return operand{literal: t.text}, nil
+	}
+	return operand{}, fmt.Errorf("at %d: expected field or literal", t.pos)
+}
+
+func (p *parser) fieldList() ([]string, error) {
+	if !p.punct("(") {
+		return nil, p.errorf("expected (")
+	}
+	var fields []string
+	for {
+		t := p.next()
+		if t.kind != "ident" {
+			return nil, fmt.Errorf("at %d: expected field name", t.pos)
+		}
+		fields = append(fields, t.text)
+		if p.punct(")") {
+			return fields, nil
+		}
+		if !p.punct(",") {
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
+
+func (p *parser) literalList() ([]string, error) {
+	if !p.punct("(") {
+		return nil, p.errorf("expected (")
+	}
+	var list []string
+	for {
+		t := p.next()
+		if t.kind != "string" && t.kind != "number" {
+			return nil, fmt.Errorf("at %d: expected literal", t.pos)
+		}
+		list = append(list, t.text)
+		if p.punct(")") {
+			return list, nil
+		}
+		if !p.punct(",") {
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
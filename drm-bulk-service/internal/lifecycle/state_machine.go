@@ -0,0 +1,136 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Resource statuses as defined by the Inventory proto (ResourceStatus enum)
+const (
+	StatusCreated   = "Created"
+	StatusAvailable = "Available"
+	StatusReserved  = "Reserved"
+	StatusInUse     = "InUse"
+	StatusRetired   = "Retired"
+	StatusDisabled  = "Disabled"
+)
+
+// Statuses lists every known resource status in proto order
+var Statuses = []string{
+	StatusCreated,
+	StatusAvailable,
+	StatusReserved,
+	StatusInUse,
+	StatusRetired,
+	StatusDisabled,
+}
+
+// DefaultTransitions is used when STATUS_TRANSITIONS is not configured.
+// Format: "<from>:<to>,<to>;<from>:<to>" (a status with no targets is terminal)
+const DefaultTransitions = "Created:Available,Disabled,Retired;" +
+	"Available:Reserved,InUse,Disabled,Retired;" +
+	"Reserved:Available,InUse,Retired;" +
+	"InUse:Available,Disabled;" +
+	"Disabled:Available,Retired;" +
+	"Retired:"
+
+// ErrIllegalTransition is matched by every TransitionError via errors.Is
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// TransitionError describes a rejected status change
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("illegal transition: %s -> %s (%s)", e.From, e.To, e.Reason)
+	}
+	return fmt.Sprintf("illegal transition: %s -> %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// StateMachine holds the allowed resourceStatus transitions
+type StateMachine struct {
+	allowed map[string]map[string]bool
+}
+
+// Parse builds a StateMachine from a transition spec (see DefaultTransitions).
+// An empty spec yields the default rules
+func Parse(spec string) (*StateMachine, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultTransitions
+	}
+
+	sm := &StateMachine{allowed: map[string]map[string]bool{}}
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		from, targets, ok := strings.Cut(rule, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid transition rule %q: expected <from>:<to>,...", rule)
+		}
+		from = strings.TrimSpace(from)
+		if !IsKnown(from) {
+			return nil, fmt.Errorf("invalid transition rule %q: unknown status %q", rule, from)
+		}
+
+		if sm.allowed[from] == nil {
+			sm.allowed[from] = map[string]bool{}
+		}
+		for _, to := range strings.Split(targets, ",") {
+			to = strings.TrimSpace(to)
+			if to == "" {
+				continue
+			}
+			if !IsKnown(to) {
+				return nil, fmt.Errorf("invalid transition rule %q: unknown status %q", rule, to)
+			}
+			sm.allowed[from][to] = true
+		}
+	}
+	return sm, nil
+}
+
+// Default returns the StateMachine built from DefaultTransitions
+func Default() *StateMachine {
+	sm, _ := Parse(DefaultTransitions)
+	return sm
+}
+
+// Validate checks whether a resource in status "from" may move to status "to".
+// Resources without a status are treated as Created
+func (sm *StateMachine) Validate(from, to string) error {
+	if from == "" {
+		from = StatusCreated
+	}
+	if !IsKnown(to) {
+		return &TransitionError{From: from, To: to, Reason: "unknown target status"}
+	}
+	if from == to {
+		return &TransitionError{From: from, To: to, Reason: "resource is already " + to}
+	}
+	if !sm.allowed[from][to] {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// IsKnown reports whether status is one of the proto ResourceStatus values
+func IsKnown(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
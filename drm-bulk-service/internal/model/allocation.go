@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AllocationCriteria selects the Available logical resources an allocation may claim
+type AllocationCriteria struct {
+	Type         string `json:"type"`                   // e.g. MSISDN
+	Category     string `json:"category,omitempty"`     // category entry or MobileClass characteristic, e.g. Gold
+	ValuePattern string `json:"valuePattern,omitempty"` // digits with x as wildcard, e.g. 8007xxxxx
+
+	// NumericOrder claims the numerically lowest value first (see NumericValueTypes)
+	NumericOrder bool `json:"-"`
+}
+
+// Allocation marks a resource as claimed by an allocation BulkRequest
+type Allocation struct {
+	BulkRequestID string    `bson:"bulkRequestId" json:"bulkRequestId"`
+	AllocatedAt   time.Time `bson:"allocatedAt" json:"allocatedAt"`
+}
@@ -12,6 +12,28 @@ type ResourceCharacteristic struct {
 	Value string `bson:"value" json:"value"`
 }
 
+// SecretCharacteristics are characteristic codes (SIM PIN/PUK) that are never
+// public identifiers and never written to reports
+var SecretCharacteristics = map[string]bool{"PIN1": true, "PUK1": true, "PIN2": true, "PUK2": true}
+
+type CompanionResource struct {
+	Type     string `bson:"type" json:"type"`
+	BaseType string `bson:"baseType" json:"baseType"`
+	Value    string `bson:"value" json:"value"`
+}
+
+// FieldChange is the value of one inventory field before and after a bulk update
+type FieldChange struct {
+	Before string `bson:"before" json:"before"`
+	After  string `bson:"after" json:"after"`
+}
+
+// ItemSucceeded reports whether a final item status counts as a success.
+// "unchanged" is an update that matched but found every value already set
+func ItemSucceeded(status string) bool {
+	return status == "success" || status == "unchanged"
+}
+
 type BulkItem struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	BulkRequestID primitive.ObjectID `bson:"bulkRequestId"`
@@ -20,14 +42,41 @@ type BulkItem struct {
 	Type     string `bson:"type" json:"type"`
 	BaseType string `bson:"baseType" json:"baseType"`
 
+	// Source of the item: line in the uploaded file and the row as it was read
+	LineNumber int      `bson:"lineNumber,omitempty" json:"lineNumber,omitempty"`
+	RawRow     []string `bson:"rawRow,omitempty" json:"rawRow,omitempty"`
+
 	Status       string `bson:"status" json:"status"`
 	ErrorMessage string `bson:"errorMessage,omitempty" json:"errorMessage,omitempty"`
 
+	// Machine readable failure: a stable code (e.g. ALREADY_EXISTS, ICCID_CHECK_DIGIT)
+	// and its category (validation, duplicate, not_found, ...; see ErrorCategory*)
+	ErrorCode     string `bson:"errorCode,omitempty" json:"errorCode,omitempty"`
+	ErrorCategory string `bson:"errorCategory,omitempty" json:"errorCategory,omitempty"`
+
 	// For bulk create report
 	ResourceCharacteristic []ResourceCharacteristic `bson:"resourceCharacteristic,omitempty" json:"resourceCharacteristic,omitempty"`
 
 	UpdateFields map[string]string `bson:"updateFields,omitempty" json:"updateFields,omitempty"`
 
+	// For bulk update: previous and new value of every updated field (the diff report)
+	Changes map[string]FieldChange `bson:"changes,omitempty" json:"changes,omitempty"`
+
+	// Optimistic concurrency guard for bulk update ("expected.<field>" columns):
+	// the update only applies while the inventory document still has these values
+	Expected map[string]string `bson:"expected,omitempty" json:"expected,omitempty"`
+
+	// For bulk create: an extra resource created in the same row (e.g. the logical IP
+	// of a router). It relies on the item's resource; the row succeeds or rolls back as a unit
+	Companion *CompanionResource `bson:"companion,omitempty" json:"companion,omitempty"`
+
+	// For bulk link: relationship to attach from this item's resource to a target
+	Link *LinkSpec `bson:"link,omitempty" json:"link,omitempty"`
+
+	// For bulk status change: requested target and the status found in inventory
+	FromStatus string `bson:"fromStatus,omitempty" json:"fromStatus,omitempty"`
+	ToStatus   string `bson:"toStatus,omitempty" json:"toStatus,omitempty"`
+
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BulkItemSnapshot is the before-image of the inventory fields a bulk update item
+// overwrote. It is read atomically with the update, stored once the update modified
+// the document and used to roll the update back
+type BulkItemSnapshot struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BulkRequestID primitive.ObjectID `bson:"bulkRequestId" json:"bulkRequestId"`
+	BulkItemID    primitive.ObjectID `bson:"bulkItemId" json:"bulkItemId"`
+
+	Value    string `bson:"value" json:"value"`
+	Type     string `bson:"type" json:"type"`
+	BaseType string `bson:"baseType" json:"baseType"`
+
+	// Previous value of every field the update sets; Missing lists fields
+	// that did not exist on the document before the update
+	Before  map[string]interface{} `bson:"before" json:"before"`
+	Missing []string               `bson:"missing,omitempty" json:"missing,omitempty"`
+
+	// AppliedAt is the updatedAt the bulk update wrote.
+	// A document whose updatedAt differs was modified again after the bulk update
+	AppliedAt *time.Time `bson:"appliedAt,omitempty" json:"appliedAt,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,15 @@
+package model
+
+// BulkItemSummary aggregates the items of one BulkRequest for its report
+type BulkItemSummary struct {
+	Total           int
+	StatusCounts    map[string]int
+	CategoryCounts  map[string]int
+	ErrorCodeCounts map[string]int
+
+	// RawRowWidth is the widest uploaded row (0 when no item carries its raw row)
+	RawRowWidth int
+
+	// UpdateFields are the field names set by any item of a bulk update, sorted
+	UpdateFields []string
+}
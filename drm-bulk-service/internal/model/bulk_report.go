@@ -9,10 +9,25 @@ import (
 type BulkReport struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	RequestID    primitive.ObjectID `bson:"requestId" json:"requestId"`
+	Version      int                `bson:"version" json:"version"` // 1 at finalization, +1 per regeneration
 	TotalItems   int                `bson:"totalItems" json:"totalItems"`
 	SuccessCount int                `bson:"successCount" json:"successCount"`
 	FailureCount int                `bson:"failureCount" json:"failureCount"`
-	FileID       primitive.ObjectID `bson:"fileId" json:"fileId"`
-	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+	StatusCounts map[string]int     `bson:"statusCounts,omitempty" json:"statusCounts,omitempty"`
+
+	// Failed items per error category and per error code ("312 duplicates, 4 timeouts")
+	CategoryCounts  map[string]int `bson:"categoryCounts,omitempty" json:"categoryCounts,omitempty"`
+	ErrorCodeCounts map[string]int `bson:"errorCodeCounts,omitempty" json:"errorCodeCounts,omitempty"`
+
+	FileID primitive.ObjectID `bson:"fileId" json:"fileId"`
+
+	// Formats maps each generated report format (csv, json, ndjson, xlsx) to its GridFS file.
+	// CSV is written at finalization, the others on first download
+	Formats map[string]primitive.ObjectID `bson:"formats,omitempty" json:"formats,omitempty"`
+
+	// FailuresFileID holds only the failed rows in the uploaded format, ready to fix and resubmit
+	FailuresFileID *primitive.ObjectID `bson:"failuresFileId,omitempty" json:"failuresFileId,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
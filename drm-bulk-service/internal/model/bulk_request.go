@@ -10,11 +10,18 @@ type BulkRequest struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 
 	// Metadata
-	Type     string `bson:"type" json:"type"`
-	BaseType string `bson:"baseType" json:"baseType"`
+	Operation string `bson:"operation,omitempty" json:"operation,omitempty"` // create | update | status | retire | delete | rollback | link | sim | reserve | release | allocate
+	Type      string `bson:"type" json:"type"`
+	BaseType  string `bson:"baseType" json:"baseType"`
 
 	FileName string `bson:"fileName" json:"fileName"`
 
+	// InputHeader names the columns of the uploaded file; reports repeat it
+	InputHeader []string `bson:"inputHeader,omitempty" json:"inputHeader,omitempty"`
+	// InputHasHeader is true when InputHeader is the file's own header line
+	// (it is then written back at the top of the failures-only report)
+	InputHasHeader bool `bson:"inputHasHeader,omitempty" json:"inputHasHeader,omitempty"`
+
 	// User info
 	UserName     string `bson:"userName" json:"userName"`
 	UserRole     string `bson:"userRole" json:"userRole"`
@@ -27,6 +34,9 @@ type BulkRequest struct {
 	SuccessCount   int `bson:"successCount" json:"successCount"`
 	FailureCount   int `bson:"failureCount" json:"failureCount"`
 
+	// Item count per final item status (success, failure, conflict, ...)
+	StatusCounts map[string]int `bson:"statusCounts,omitempty" json:"statusCounts,omitempty"`
+
 	// Status & progress
 	Status          string `bson:"status" json:"status"` // pending | processing | completed | failed
 	ProgressPercent int    `bson:"progressPercent" json:"progressPercent"`
@@ -36,6 +46,14 @@ type BulkRequest struct {
 	UpdatedAt   time.Time  `bson:"updatedAt" json:"updatedAt"`
 	CompletedAt *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
 
+	// Retention: when the items of the request and when expired report versions were purged.
+	// The request document and its counts are kept
+	ItemsPurgedAt   *time.Time `bson:"itemsPurgedAt,omitempty" json:"itemsPurgedAt,omitempty"`
+	ReportsPurgedAt *time.Time `bson:"reportsPurgedAt,omitempty" json:"reportsPurgedAt,omitempty"`
+
+	// ParentRequestID links a derived request (e.g. a rollback) to the request it acts on
+	ParentRequestID string `bson:"parentRequestId,omitempty" json:"parentRequestId,omitempty"`
+
 	SchemaID   string `bson:"schemaId,omitempty" json:"schemaId,omitempty"`
 	CategoryID string `bson:"categoryId,omitempty" json:"categoryId,omitempty"`
 }
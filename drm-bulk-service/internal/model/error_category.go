@@ -0,0 +1,11 @@
+package model
+
+// Error categories of failed bulk items, used to group failures in reports
+const (
+	ErrorCategoryValidation           = "validation"
+	ErrorCategoryDuplicate            = "duplicate"
+	ErrorCategoryNotFound             = "not_found"
+	ErrorCategoryInventoryUnavailable = "inventory_unavailable"
+	ErrorCategoryConflict             = "conflict"
+	ErrorCategoryInternal             = "internal"
+)
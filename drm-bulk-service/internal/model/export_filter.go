@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// ExportFilter is a boolean filter over inventory resources. The conditions set on
+// one node must all match; And / Or combine child nodes. For example
+// {"category": ["Gold"], "or": [{"characteristics": [{"code": "MobileClass", "value": "Gold"}]}, {"relatedPartyName": "ACME"}]}
+type ExportFilter struct {
+	And []ExportFilter `bson:"and,omitempty" json:"and,omitempty"`
+	Or  []ExportFilter `bson:"or,omitempty" json:"or,omitempty"`
+
+	Type           string `bson:"type,omitempty" json:"type,omitempty"`
+	ResourceStatus string `bson:"resourceStatus,omitempty" json:"resourceStatus,omitempty"`
+
+	// Membership: the resource has at least one of the listed values
+	Category     []string `bson:"category,omitempty" json:"category,omitempty"`
+	BusinessType []string `bson:"businessType,omitempty" json:"businessType,omitempty"`
+
+	// Date ranges (inclusive, RFC3339)
+	CreatedFrom *time.Time `bson:"createdFrom,omitempty" json:"createdFrom,omitempty"`
+	CreatedTo   *time.Time `bson:"createdTo,omitempty" json:"createdTo,omitempty"`
+	UpdatedFrom *time.Time `bson:"updatedFrom,omitempty" json:"updatedFrom,omitempty"`
+	UpdatedTo   *time.Time `bson:"updatedTo,omitempty" json:"updatedTo,omitempty"`
+
+	// RelatedPartyName matches relatedParty.name
+	RelatedPartyName string `bson:"relatedPartyName,omitempty" json:"relatedPartyName,omitempty"`
+
+	// Characteristics must each be present as a resourceCharacteristic code/value pair
+	Characteristics []CharacteristicMatch `bson:"characteristics,omitempty" json:"characteristics,omitempty"`
+}
+
+// CharacteristicMatch is a resourceCharacteristic code/value pair, e.g. MobileClass=Gold
+type CharacteristicMatch struct {
+	Code  string `bson:"code" json:"code"`
+	Value string `bson:"value" json:"value"`
+}
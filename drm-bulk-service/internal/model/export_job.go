@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportQuery selects and shapes the inventory resources of an export
+// (the query params of GET /v1/drm-bulk/resources/export)
+type ExportQuery struct {
+	BaseType       string `bson:"baseType" json:"baseType"` // LogicalResource | PhysicalResource
+	Type           string `bson:"type,omitempty" json:"type,omitempty"`
+	ResourceStatus string `bson:"resourceStatus,omitempty" json:"resourceStatus,omitempty"`
+	ValueFrom      string `bson:"valueFrom,omitempty" json:"valueFrom,omitempty"`
+	ValueTo        string `bson:"valueTo,omitempty" json:"valueTo,omitempty"`
+
+	// NumericOrder compares and sorts values as numbers (collation numericOrdering)
+	NumericOrder bool `bson:"numericOrder,omitempty" json:"numericOrder,omitempty"`
+
+	// Filter holds the category / businessType / date / relatedParty / characteristic
+	// conditions with their AND/OR combination
+	Filter *ExportFilter `bson:"filter,omitempty" json:"filter,omitempty"`
+
+	// Fields are the CSV columns, "value" first: field names, dot paths
+	// (cost.taxedValue, relatedParty.0.name) or char:<Code> characteristic values
+	Fields []string `bson:"fields" json:"fields"`
+
+	// ArrayFormat renders arrays of objects and embedded documents: json | flat
+	ArrayFormat string `bson:"arrayFormat,omitempty" json:"arrayFormat,omitempty"`
+
+	Limit int64 `bson:"limit,omitempty" json:"limit,omitempty"` // 0 exports every match
+}
+
+// ExportJob is an asynchronous export written to GridFS (bulk_exports)
+type ExportJob struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	Query ExportQuery `bson:"query" json:"query"`
+
+	// User info
+	UserName string `bson:"userName" json:"userName"`
+	UserRole string `bson:"userRole" json:"userRole"`
+
+	// Status & result
+	Status       string              `bson:"status" json:"status"` // pending | processing | completed | failed
+	RowCount     int                 `bson:"rowCount" json:"rowCount"`
+	FileID       *primitive.ObjectID `bson:"fileId,omitempty" json:"fileId,omitempty"`
+	FileName     string              `bson:"fileName,omitempty" json:"fileName,omitempty"`
+	ErrorMessage string              `bson:"errorMessage,omitempty" json:"errorMessage,omitempty"`
+
+	// Time tracking
+	CreatedAt   time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time  `bson:"updatedAt" json:"updatedAt"`
+	CompletedAt *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+}
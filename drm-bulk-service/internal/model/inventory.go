@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InventoryResource is the subset of a logicalresources / physicalresources
+// document the bulk service needs to make decisions about a resource
+type InventoryResource struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Type           string             `bson:"type" json:"type"`
+	BaseType       string             `bson:"baseType" json:"baseType"`
+	Value          string             `bson:"value" json:"value"`
+	ResourceStatus string             `bson:"resourceStatus" json:"resourceStatus"`
+
+	Reservation *Reservation `bson:"reservation,omitempty" json:"reservation,omitempty"`
+}
+
+// InventoryUpdateResult describes the outcome of a single inventory update
+type InventoryUpdateResult struct {
+	Matched   int64
+	Modified  int64
+	UpdatedAt time.Time // updatedAt written on the document; zero when nothing was modified
+
+	// Before holds the previous value of every updated field, read atomically with
+	// the update; Missing lists the fields the document did not have
+	Before  map[string]interface{}
+	Missing []string
+}
+
+// RelatedParty mirrors an entry of relatedParty on an inventory document
+type RelatedParty struct {
+	Name     string `bson:"name" json:"name"`
+	Role     string `bson:"role" json:"role"`
+	Type     string `bson:"type,omitempty" json:"type,omitempty"`
+	BaseType string `bson:"baseType,omitempty" json:"baseType,omitempty"`
+
+	// BulkRequestID marks an entry a bulk reservation or allocation added, so that
+	// releasing it never removes a party the resource already had
+	BulkRequestID string `bson:"bulkRequestId,omitempty" json:"bulkRequestId,omitempty"`
+}
+
+// Reservation is kept on a Reserved inventory document until it expires
+// and the reservation sweeper releases the resource back to Available
+type Reservation struct {
+	Party         RelatedParty `bson:"party" json:"party"`
+	ExpiresAt     time.Time    `bson:"expiresAt" json:"expiresAt"`
+	BulkRequestID string       `bson:"bulkRequestId,omitempty" json:"bulkRequestId,omitempty"`
+}
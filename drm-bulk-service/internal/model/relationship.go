@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// RelationshipTypes are the resourceRelationship types accepted by Inventory
+var RelationshipTypes = []string{"reliesOn", "bundle", "dependency", "starterPack", "capacity", "pool"}
+
+// ResourceRelationship mirrors an entry of resourceRelationship on an inventory document
+type ResourceRelationship struct {
+	RelationshipType string      `bson:"relationshipType" json:"relationshipType"`
+	ValidFor         *ValidFor   `bson:"validFor,omitempty" json:"validFor,omitempty"`
+	Resource         ResourceRef `bson:"resource" json:"resource"`
+}
+
+// ValidFor is the validity window of a relationship
+type ValidFor struct {
+	StartDateTime *time.Time `bson:"startDateTime,omitempty" json:"startDateTime,omitempty"`
+	EndDateTime   *time.Time `bson:"endDateTime,omitempty" json:"endDateTime,omitempty"`
+}
+
+// ResourceRef points at another inventory resource by id
+type ResourceRef struct {
+	ID       string `bson:"id" json:"id"`
+	Type     string `bson:"type" json:"type"`
+	BaseType string `bson:"baseType" json:"baseType"`
+}
+
+// LinkSpec is one requested relationship of a bulk link item; the source
+// resource is the item's own Value/Type/BaseType
+type LinkSpec struct {
+	RelationshipType string `bson:"relationshipType" json:"relationshipType"`
+	TargetValue      string `bson:"targetValue" json:"targetValue"`
+	TargetType       string `bson:"targetType,omitempty" json:"targetType,omitempty"`
+	TargetBaseType   string `bson:"targetBaseType" json:"targetBaseType"`
+	StartDateTime    string `bson:"startDateTime,omitempty" json:"startDateTime,omitempty"`
+	EndDateTime      string `bson:"endDateTime,omitempty" json:"endDateTime,omitempty"`
+}
+
+// IsRelationshipType reports whether t is a known relationship type
+func IsRelationshipType(t string) bool {
+	for _, rt := range RelationshipTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
@@ -12,10 +12,18 @@ type ResourceSchema struct {
 	Required   []string                  `bson:"required" json:"required"`
 }
 
+// ValidationRule is a named cross-field / cross-row rule evaluated at ingest,
+// e.g. {name: "gold-class", expr: `if category == "Gold" then MobileClass in ("Platinum", "Gold")`}
+type ValidationRule struct {
+	Name string `bson:"name" json:"name"`
+	Expr string `bson:"expr" json:"expr"`
+}
+
 type Schema struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Version        int                `bson:"version" json:"version"`
 	Name           string             `bson:"name" json:"name"`
 	Description    string             `bson:"description" json:"description"`
 	ResourceSchema ResourceSchema     `bson:"resourceSchema" json:"resourceSchema"`
+	Rules          []ValidationRule   `bson:"rules,omitempty" json:"rules,omitempty"`
 }
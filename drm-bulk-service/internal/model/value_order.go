@@ -0,0 +1,38 @@
+package model
+
+import "strings"
+
+// NumericValueTypes is the set of resource types whose values compare as numbers,
+// so that 9 sorts before 10000 in ranges and ordering
+type NumericValueTypes map[string]bool
+
+// ParseNumericValueTypes reads a comma separated list of resource types
+func ParseNumericValueTypes(spec string) NumericValueTypes {
+	types := NumericValueTypes{}
+	for _, t := range strings.Split(spec, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	return types
+}
+
+// Ordered reports whether values of resourceType compare numerically. Without a
+// type the values are numeric when every given bound is made of digits only
+func (n NumericValueTypes) Ordered(resourceType string, bounds ...string) bool {
+	if resourceType != "" {
+		return n[resourceType]
+	}
+
+	numeric := false
+	for _, b := range bounds {
+		if b == "" {
+			continue
+		}
+		if strings.Trim(b, "0123456789") != "" {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
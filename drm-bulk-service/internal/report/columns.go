@@ -0,0 +1,217 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"drm-bulk-service/internal/model"
+)
+
+// columns is the layout of a report: its header and how an item becomes a row.
+// A nil row is skipped
+type columns struct {
+	header []string
+	row    func(item model.BulkItem) []string
+}
+
+// lifecycleOperations report the status found in inventory and the target status
+var lifecycleOperations = map[string]bool{
+	"status": true, "retire": true, "delete": true,
+	"reserve": true, "release": true, "allocate": true,
+}
+
+// failedStatuses are the item outcomes an operator fixes and resubmits
+var failedStatuses = []string{"failure", "conflict", "rolled_back"}
+
+// reportColumns picks the layout of the main report. Uploaded rows are reported
+// as they were read (see rawColumns); items without a raw row use the fixed
+// layout of their operation
+func reportColumns(req model.BulkRequest, summary *model.BulkItemSummary) columns {
+	// Bulk update: before/after pair per updated field
+	var diff []string
+	if req.Operation == "update" {
+		diff = summary.UpdateFields
+	}
+
+	if summary.RawRowWidth > 0 {
+		return rawColumns(req, summary.RawRowWidth, diff)
+	}
+
+	switch {
+	case lifecycleOperations[req.Operation]:
+		return columns{
+			header: []string{"Value", "FromStatus", "ToStatus", "Status", "ErrorCode", "ErrorMessage"},
+			row: func(item model.BulkItem) []string {
+				return []string{item.Value, item.FromStatus, item.ToStatus, item.Status, item.ErrorCode, item.ErrorMessage}
+			},
+		}
+
+	// LINK report: one row per requested relationship
+	case req.Operation == "link":
+		return columns{
+			header: []string{"SourceValue", "TargetValue", "RelationshipType", "Status", "ErrorCode", "ErrorMessage"},
+			row: func(item model.BulkItem) []string {
+				var target, relType string
+				if item.Link != nil {
+					target = item.Link.TargetValue
+					relType = item.Link.RelationshipType
+				}
+				return []string{item.Value, target, relType, item.Status, item.ErrorCode, item.ErrorMessage}
+			},
+		}
+
+	// SIM batch report (PIN/PUK are deliberately left out)
+	case req.Operation == "sim":
+		return columns{
+			header: []string{"ICCID", "IMSI", "MSISDN", "Status", "ErrorCode", "ErrorMessage"},
+			row: func(item model.BulkItem) []string {
+				chars := characteristics(item)
+				return []string{chars["ICCID"], chars["IMSI"], chars["MSISDN"], item.Status, item.ErrorCode, item.ErrorMessage}
+			},
+		}
+
+	case req.Operation == "update":
+		return columns{
+			header: append(append([]string{"Value"}, diffHeader(diff)...), "Status", "ErrorCode", "ErrorMessage"),
+			row: func(item model.BulkItem) []string {
+				row := append([]string{item.Value}, diffCells(diff, item)...)
+				return append(row, item.Status, item.ErrorCode, item.ErrorMessage)
+			},
+		}
+
+	case req.Operation == "rollback":
+		return columns{
+			header: []string{"Value", "Name", "Status", "ErrorCode", "ErrorMessage"},
+			row: func(item model.BulkItem) []string {
+				return []string{item.Value, item.UpdateFields["name"], item.Status, item.ErrorCode, item.ErrorMessage}
+			},
+		}
+	}
+
+	// CREATE report (MSISDN/MobileClass)
+	return columns{
+		header: []string{"MSISDN", "MobileClass", "Status", "ErrorCode", "ErrorMessage"},
+		row: func(item model.BulkItem) []string {
+			chars := characteristics(item)
+			return []string{chars["MSISDN"], chars["MobileClass"], item.Status, item.ErrorCode, item.ErrorMessage}
+		},
+	}
+}
+
+// rawColumns repeats the original columns of every row, followed by
+// FromStatus/ToStatus for lifecycle operations, the before/after pairs of diff
+// and Status, ErrorCode, ErrorMessage. Secret columns (PIN/PUK) are blanked
+func rawColumns(req model.BulkRequest, width int, diff []string) columns {
+	if len(req.InputHeader) > width {
+		width = len(req.InputHeader)
+	}
+
+	header := make([]string, width)
+	for i := range header {
+		if i < len(req.InputHeader) {
+			header[i] = req.InputHeader[i]
+		} else {
+			header[i] = fmt.Sprintf("Column%d", i+1)
+		}
+	}
+	secret := secretColumns(req.InputHeader)
+
+	lifecycle := lifecycleOperations[req.Operation]
+	if lifecycle {
+		header = append(header, "FromStatus", "ToStatus")
+	}
+	header = append(header, diffHeader(diff)...)
+	header = append(header, "Status", "ErrorCode", "ErrorMessage")
+
+	return columns{
+		header: header,
+		row: func(item model.BulkItem) []string {
+			raw := item.RawRow
+			if len(raw) == 0 {
+				raw = []string{item.Value}
+			}
+
+			row := make([]string, width, width+2*len(diff)+5)
+			for i := range row {
+				if i < len(raw) && !secret[i] {
+					row[i] = raw[i]
+				}
+			}
+			if lifecycle {
+				row = append(row, item.FromStatus, item.ToStatus)
+			}
+			row = append(row, diffCells(diff, item)...)
+			return append(row, item.Status, item.ErrorCode, item.ErrorMessage)
+		},
+	}
+}
+
+// failuresColumns writes failed rows as they were uploaded, under the file's
+// header line when it had one, so the file can be fixed and resubmitted with the
+// same settings. Secret columns (PIN/PUK) are blanked and must be re-supplied
+func failuresColumns(req model.BulkRequest) columns {
+	var header []string
+	if req.InputHasHeader {
+		header = req.InputHeader
+	}
+	secret := secretColumns(req.InputHeader)
+	return columns{
+		header: header,
+		row: func(item model.BulkItem) []string {
+			if len(item.RawRow) == 0 {
+				return nil
+			}
+			row := make([]string, len(item.RawRow))
+			for i, v := range item.RawRow {
+				if !secret[i] {
+					row[i] = v
+				}
+			}
+			return row
+		},
+	}
+}
+
+// secretColumns returns the indexes of the input columns named like a
+// SecretCharacteristic (PIN/PUK)
+func secretColumns(header []string) map[int]bool {
+	secret := map[int]bool{}
+	for i, name := range header {
+		if model.SecretCharacteristics[strings.ToUpper(strings.TrimSpace(name))] {
+			secret[i] = true
+		}
+	}
+	return secret
+}
+
+// diffHeader names the before/after column pair of every updated field (name.before, name.after)
+func diffHeader(fields []string) []string {
+	header := make([]string, 0, 2*len(fields))
+	for _, f := range fields {
+		header = append(header, f+".before", f+".after")
+	}
+	return header
+}
+
+// diffCells are the recorded before/after values of an item; fields the item did not
+// update, or updates that never applied, are left blank
+func diffCells(fields []string, item model.BulkItem) []string {
+	cells := make([]string, 0, 2*len(fields))
+	for _, f := range fields {
+		change, ok := item.Changes[f]
+		if !ok {
+			cells = append(cells, "", "")
+			continue
+		}
+		cells = append(cells, change.Before, change.After)
+	}
+	return cells
+}
+
+func characteristics(item model.BulkItem) map[string]string {
+	chars := map[string]string{}
+	for _, rc := range item.ResourceCharacteristic {
+		chars[rc.Code] = rc.Value
+	}
+	return chars
+}
@@ -4,18 +4,25 @@ import (
 	"context"
 	"drm-bulk-service/internal/model"
 	"drm-bulk-service/internal/repository"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"os"
-	"path/filepath"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 )
 
+// ErrItemsPurged is returned when a report would have to be rebuilt from items the
+// retention policy already deleted
+var ErrItemsPurged = errors.New("the items of this request were purged by the retention policy")
+
+// ErrVersionSuperseded is returned when a format that is generated on demand is
+// requested for a report version that is no longer the latest: the items have
+// moved on, so it would not match that version's CSV
+var ErrVersionSuperseded = errors.New("this format is only generated for the latest report version")
+
 type Service struct {
 	itemRepo   *repository.BulkItemRepository
 	reportRepo *repository.BulkReportRepository
@@ -37,6 +44,8 @@ func NewService(
 /*
 ===========================
 Finalize Bulk (ONE TIME)
+
+Stores version 1 of the report; later versions come from Regenerate.
 ===========================
 */
 func (s *Service) Finalize(ctx context.Context, req model.BulkRequest) error {
@@ -45,215 +54,344 @@ func (s *Service) Finalize(ctx context.Context, req model.BulkRequest) error {
 		return nil
 	}
 
-	items, err := s.itemRepo.FindByBulkRequestID(ctx, req.ID.Hex())
-	if err != nil {
-		return err
+	_, err := s.generate(ctx, req, 1)
+	return err
+}
+
+/*
+===========================
+Regenerate
+
+Writes a new report version from the current item states, e.g. after
+retries or manual fixes. Earlier versions are kept.
+===========================
+*/
+func (s *Service) Regenerate(ctx context.Context, req model.BulkRequest) (*model.BulkReport, error) {
+	if req.ItemsPurgedAt != nil {
+		return nil, ErrItemsPurged
 	}
 
-	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("bulk_%s.csv", req.ID.Hex()))
+	version := 1
+	latest, err := s.reportRepo.FindByRequestID(ctx, req.ID.Hex())
+	switch {
+	case err == nil:
+		version = latest.Version + 1
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return nil, err
+	}
 
-	if err := writeCSV(tmp, items); err != nil {
-		log.Printf("Failed to write CSV: %v", err)
-		return err
+	return s.generate(ctx, req, version)
+}
+
+// generate writes one report version. Counts come from one aggregation; the report
+// files are streamed from a cursor straight into GridFS, so no item list is held in
+// memory and no temp file is written. A failed upload is aborted and files already
+// stored for this version are removed again
+func (s *Service) generate(ctx context.Context, req model.BulkRequest, version int) (*model.BulkReport, error) {
+	summary, err := s.itemRepo.Summarize(ctx, req.ID.Hex())
+	if err != nil {
+		log.Printf("Failed to summarize items: %v", err)
+		return nil, err
 	}
 
-	fileID, err := storeGridFS(ctx, s.db, tmp, req.ID.Hex())
+	bucket, err := gridfs.NewBucket(s.db)
 	if err != nil {
-		log.Printf("Failed to store GridFS: %v", err)
-		return err
+		return nil, fmt.Errorf("failed to create GridFS bucket: %w", err)
+	}
+
+	fileID, err := s.upload(ctx, bucket, FileName(req.ID.Hex(), version, FormatCSV), req.ID.Hex(), nil,
+		FormatCSV, reportColumns(req, summary))
+	if err != nil {
+		log.Printf("Failed to store report in GridFS: %v", err)
+		return nil, err
+	}
+
+	// Second artifact: failed rows only, in the uploaded format
+	var failuresFileID *primitive.ObjectID
+	if summary.RawRowWidth > 0 {
+		id, err := s.upload(ctx, bucket, FailuresFileName(req.ID.Hex(), version), req.ID.Hex(), failedStatuses,
+			FormatCSV, failuresColumns(req))
+		if err != nil {
+			log.Printf("Failed to store failures in GridFS: %v", err)
+			deleteFile(bucket, fileID)
+			return nil, err
+		}
+		failuresFileID = &id
+	}
+
+	// counted like the BulkRequest (completeRequest): every status that is not a success fails
+	success, failure := 0, 0
+	for status, n := range summary.StatusCounts {
+		if model.ItemSucceeded(status) {
+			success += n
+		} else {
+			failure += n
+		}
 	}
 
 	report := model.BulkReport{
 		RequestID:    req.ID,
-		TotalItems:   len(items),
-		SuccessCount: count(items, "success"),
-		FailureCount: count(items, "failure"),
+		Version:      version,
+		TotalItems:   summary.Total,
+		SuccessCount: success,
+		FailureCount: failure,
+		StatusCounts: summary.StatusCounts,
 		FileID:       fileID,
+		Formats:      map[string]primitive.ObjectID{FormatCSV: fileID},
+
+		FailuresFileID: failuresFileID,
+
+		CategoryCounts:  summary.CategoryCounts,
+		ErrorCodeCounts: summary.ErrorCodeCounts,
 	}
 
 	if err := s.reportRepo.Create(ctx, &report); err != nil {
 		log.Printf("Failed to create report document: %v", err)
-		return err
+		deleteFile(bucket, fileID)
+		if failuresFileID != nil {
+			deleteFile(bucket, *failuresFileID)
+		}
+		return nil, err
 	}
-	log.Printf("Report finalized: requestId=%s fileID=%s", req.ID.Hex(), fileID.Hex())
-	return nil
-
+	log.Printf("Report finalized: requestId=%s version=%d fileID=%s", req.ID.Hex(), version, fileID.Hex())
+	return &report, nil
 }
 
 /*
 ===========================
-Helpers
+Report file per format
+
+CSV is stored at finalization; JSON, NDJSON and XLSX are generated on
+first download from the items and cached in GridFS, recorded under
+BulkReport.Formats. Only the latest version generates new formats: an
+older version keeps the formats it already has.
 ===========================
 */
-func count(items []model.BulkItem, status string) int {
-	total := 0
-	for _, i := range items {
-		if i.Status == status {
-			total++
-		}
+func (s *Service) File(
+	ctx context.Context,
+	req model.BulkRequest,
+	report *model.BulkReport,
+	format string,
+) (primitive.ObjectID, error) {
+	if fileID, ok := report.Formats[format]; ok {
+		return fileID, nil
+	}
+	// Reports finalized before formats were recorded only have the CSV
+	if format == FormatCSV {
+		return report.FileID, nil
+	}
+	if req.ItemsPurgedAt != nil {
+		return primitive.NilObjectID, ErrItemsPurged
 	}
-	return total
-}
 
-func writeCSV(path string, items []model.BulkItem) error {
-	file, err := os.Create(path)
+	latest, err := s.reportRepo.FindByRequestID(ctx, req.ID.Hex())
 	if err != nil {
-		return err
+		return primitive.NilObjectID, err
+	}
+	if latest.Version != report.Version {
+		return primitive.NilObjectID, ErrVersionSuperseded
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	summary, err := s.itemRepo.Summarize(ctx, req.ID.Hex())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
 
-	// detect if this is an UPDATE bulk (has UpdateFields)
-	hasUpdateFields := false
-	for _, it := range items {
-		if len(it.UpdateFields) > 0 {
-			hasUpdateFields = true
-			break
-		}
+	bucket, err := gridfs.NewBucket(s.db)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to create GridFS bucket: %w", err)
 	}
 
-	if hasUpdateFields {
-		// Header for update
-		if err := writer.Write([]string{
-			"Value",
-			"Name",
-			"Status",
-			"ErrorMessage",
-		}); err != nil {
-			return err
-		}
+	fileID, err := s.upload(ctx, bucket, FileName(req.ID.Hex(), report.Version, format), req.ID.Hex(), nil,
+		format, reportColumns(req, summary))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
 
-		// Rows for update
-		for _, item := range items {
-			value := item.Value
-			name := item.UpdateFields["name"]
-
-			if err := writer.Write([]string{
-				value,
-				name,
-				item.Status,
-				item.ErrorMessage,
-			}); err != nil {
-				return err
-			}
-		}
-		return nil
+	stored, err := s.reportRepo.AddFormat(ctx, report.ID, format, fileID)
+	if err != nil {
+		deleteFile(bucket, fileID)
+		return primitive.NilObjectID, err
+	}
+	if stored {
+		log.Printf("Report format generated: requestId=%s format=%s fileID=%s", req.ID.Hex(), format, fileID.Hex())
+		return fileID, nil
 	}
 
-	// Existing CREATE report (MSISDN/MobileClass)
-	if err := writer.Write([]string{
-		"MSISDN",
-		"MobileClass",
-		"Status",
-		"ErrorMessage",
-	}); err != nil {
-		return err
+	// A concurrent download generated this format first: keep theirs
+	deleteFile(bucket, fileID)
+	current, err := s.reportRepo.FindByRequestIDAndVersion(ctx, req.ID.Hex(), report.Version)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	fileID, ok := current.Formats[format]
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("report format %s was not recorded", format)
 	}
+	return fileID, nil
+}
 
-	for _, item := range items {
-		msisdn := ""
-		mobileClass := ""
+/*
+===========================
+Partial report
 
-		for _, rc := range item.ResourceCharacteristic {
-			switch rc.Code {
-			case "MSISDN":
-				msisdn = rc.Value
-			case "MobileClass":
-				mobileClass = rc.Value
-			}
-		}
+Streams the current state of the items (pending rows included) straight
+to w, e.g. the HTTP response, while the job is still running. Nothing is
+stored. With failuresOnly the failed rows are written in the uploaded
+format, as in the failures report.
+===========================
+*/
+func (s *Service) WritePartial(
+	ctx context.Context,
+	w io.Writer,
+	req model.BulkRequest,
+	format string,
+	failuresOnly bool,
+) error {
+	if req.ItemsPurgedAt != nil {
+		return ErrItemsPurged
+	}
 
-		if err := writer.Write([]string{
-			msisdn,
-			mobileClass,
-			item.Status,
-			item.ErrorMessage,
-		}); err != nil {
-			return err
-		}
+	if failuresOnly {
+		return s.write(ctx, w, req.ID.Hex(), failedStatuses, FormatCSV, failuresColumns(req))
 	}
 
-	return nil
+	summary, err := s.itemRepo.Summarize(ctx, req.ID.Hex())
+	if err != nil {
+		return err
+	}
+	return s.write(ctx, w, req.ID.Hex(), nil, format, reportColumns(req, summary))
 }
 
-func storeInGridFS(
-	ctx context.Context,
-	db *mongo.Database,
-	filePath string,
-	requestID string,
-) (primitive.ObjectID, error) {
+/*
+===========================
+Delete (retention)
 
-	bucket, err := gridfs.NewBucket(db)
+Removes the GridFS files of one report version, then the report
+document. A missing file counts as removed so an interrupted purge
+can simply be run again.
+===========================
+*/
+func (s *Service) Delete(ctx context.Context, report model.BulkReport) (int, error) {
+	bucket, err := gridfs.NewBucket(s.db)
 	if err != nil {
-		return primitive.NilObjectID, err
+		return 0, fmt.Errorf("failed to create GridFS bucket: %w", err)
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return primitive.NilObjectID, err
+	files := map[primitive.ObjectID]bool{report.FileID: true}
+	for _, id := range report.Formats {
+		files[id] = true
+	}
+	if report.FailuresFileID != nil {
+		files[*report.FailuresFileID] = true
 	}
-	defer file.Close()
 
-	uploadStream, err := bucket.OpenUploadStream(
-		fmt.Sprintf("bulk_report_%s.csv", requestID),
-	)
+	deleted := 0
+	for id := range files {
+		if id.IsZero() {
+			continue
+		}
+		if err := bucket.DeleteContext(ctx, id); err != nil {
+			if errors.Is(err, gridfs.ErrFileNotFound) {
+				continue
+			}
+			return deleted, fmt.Errorf("failed to delete GridFS file %s: %w", id.Hex(), err)
+		}
+		deleted++
+	}
+
+	if err := s.reportRepo.Delete(ctx, report.ID); err != nil {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+/*
+===========================
+Helpers
+===========================
+*/
+
+// upload streams the items of a request (optionally only those in statuses) in a report
+// format into a new GridFS file. On any error the upload is aborted, leaving no partial file
+func (s *Service) upload(
+	ctx context.Context,
+	bucket *gridfs.Bucket,
+	name, requestID string,
+	statuses []string,
+	format string,
+	cols columns,
+) (primitive.ObjectID, error) {
+	stream, err := bucket.OpenUploadStream(name)
 	if err != nil {
-		return primitive.NilObjectID, err
+		return primitive.NilObjectID, fmt.Errorf("failed to open upload stream: %w", err)
 	}
-	defer uploadStream.Close()
 
-	if _, err := io.Copy(uploadStream, file); err != nil {
+	if err := s.write(ctx, stream, requestID, statuses, format, cols); err != nil {
+		if abortErr := stream.Abort(); abortErr != nil {
+			log.Printf("Failed to abort GridFS upload %s: %v", name, abortErr)
+		}
 		return primitive.NilObjectID, err
 	}
+	if err := stream.Close(); err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to close upload stream: %w", err)
+	}
 
-	id, ok := uploadStream.FileID.(primitive.ObjectID)
+	fileID, ok := stream.FileID.(primitive.ObjectID)
 	if !ok {
-		return primitive.NilObjectID, fmt.Errorf("invalid GridFS FileID type")
+		return primitive.NilObjectID, fmt.Errorf("unexpected FileID type: %T", stream.FileID)
 	}
-
-	return id, nil
+	return fileID, nil
 }
 
-func storeGridFS(ctx context.Context, db *mongo.Database, path, name string) (primitive.ObjectID, error) {
-	// Create a GridFS bucket
-	bucket, err := gridfs.NewBucket(db)
-	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to create GridFS bucket: %w", err)
-	}
-
-	// Open the file
-	f, err := os.Open(path)
+// write streams the items of a request (optionally only those in statuses) from a
+// cursor through the report writer of format into w
+func (s *Service) write(
+	ctx context.Context,
+	w io.Writer,
+	requestID string,
+	statuses []string,
+	format string,
+	cols columns,
+) error {
+	cursor, err := s.itemRepo.CursorByBulkRequestID(ctx, requestID, statuses)
 	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to open file: %w", err)
+		return err
 	}
-	defer f.Close()
+	defer cursor.Close(ctx)
 
-	// Upload to GridFS
-	uploadStream, err := bucket.OpenUploadStream(name)
+	writer, err := NewWriter(format, w)
 	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to open upload stream: %w", err)
+		return err
 	}
-	defer uploadStream.Close()
-
-	if _, err := io.Copy(uploadStream, f); err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to copy file to GridFS: %w", err)
+	if cols.header != nil {
+		if err := writer.WriteHeader(cols.header); err != nil {
+			return err
+		}
 	}
 
-	// Safely get ObjectID
-	var fileID primitive.ObjectID
-	switch v := uploadStream.FileID.(type) {
-	case primitive.ObjectID:
-		fileID = v
-	case string:
-		fileID, err = primitive.ObjectIDFromHex(v)
-		if err != nil {
-			return primitive.NilObjectID, fmt.Errorf("invalid FileID string: %s", v)
+	for cursor.Next(ctx) {
+		var item model.BulkItem
+		if err := cursor.Decode(&item); err != nil {
+			return err
 		}
-	default:
-		return primitive.NilObjectID, fmt.Errorf("unexpected FileID type: %T", v)
+		row := cols.row(item)
+		if row == nil {
+			continue
+		}
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
 	}
+	return writer.Close()
+}
 
-	return fileID, nil
+// deleteFile removes a GridFS file stored for a report that could not be completed
+func deleteFile(bucket *gridfs.Bucket, id primitive.ObjectID) {
+	if err := bucket.Delete(id); err != nil {
+		log.Printf("Failed to remove GridFS file %s: %v", id.Hex(), err)
+	}
 }
@@ -0,0 +1,254 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report formats selectable with ?format= on the report download
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ParseFormat validates a requested format; empty means CSV
+func ParseFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return FormatCSV, nil
+	}
+	if _, ok := contentTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported report format %q (csv, json, ndjson, xlsx)", format)
+	}
+	return format, nil
+}
+
+// ContentType is the HTTP Content-Type of a report format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FileName is the GridFS / download name of a request's report version in a format
+func FileName(requestID string, version int, format string) string {
+	if version > 1 {
+		return fmt.Sprintf("bulk_report_%s_v%d.%s", requestID, version, format)
+	}
+	return fmt.Sprintf("bulk_report_%s.%s", requestID, format)
+}
+
+// FailuresFileName is the GridFS / download name of a report version's failed rows
+func FailuresFileName(requestID string, version int) string {
+	if version > 1 {
+		return fmt.Sprintf("bulk_failures_%s_v%d.csv", requestID, version)
+	}
+	return fmt.Sprintf("bulk_failures_%s.csv", requestID)
+}
+
+// Writer writes report rows in one output format. Close finishes the document
+// but does not close the underlying writer
+type Writer interface {
+	WriteHeader(header []string) error
+	WriteRow(row []string) error
+	Close() error
+}
+
+// NewWriter returns the report writer for format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatNDJSON:
+		return &jsonWriter{w: w, lines: true}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
+
+/*
+===========================
+CSV
+===========================
+*/
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) WriteHeader(header []string) error { return c.w.Write(header) }
+
+func (c *csvWriter) WriteRow(row []string) error { return c.w.Write(row) }
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+/*
+===========================
+JSON / NDJSON
+
+One object per row keyed by the header, in column order. JSON wraps the
+objects in an array; NDJSON writes one object per line.
+===========================
+*/
+
+type jsonWriter struct {
+	w      io.Writer
+	lines  bool
+	header []string
+	rows   int
+}
+
+func (j *jsonWriter) WriteHeader(header []string) error {
+	j.header = header
+	return nil
+}
+
+func (j *jsonWriter) WriteRow(row []string) error {
+	var b strings.Builder
+	switch {
+	case j.lines:
+	case j.rows == 0:
+		b.WriteString("[\n")
+	default:
+		b.WriteString(",\n")
+	}
+
+	b.WriteByte('{')
+	for i, value := range row {
+		key := fmt.Sprintf("Column%d", i+1)
+		if i < len(j.header) {
+			key = j.header[i]
+		}
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(value)
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	if j.lines {
+		b.WriteByte('\n')
+	}
+
+	j.rows++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.lines {
+		return nil
+	}
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+/*
+===========================
+XLSX
+
+A single-sheet workbook written with archive/zip. The package parts are
+written up front and the sheet is streamed row by row; every cell is an
+inline string so values such as ICCIDs keep their leading digits.
+===========================
+*/
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	z := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zip: z, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteHeader(header []string) error { return x.WriteRow(header) }
+
+func (x *xlsxWriter) WriteRow(row []string) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, value := range row {
+		b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(&b, []byte(value)); err != nil {
+			return err
+		}
+		b.WriteString("</t></is></c>")
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
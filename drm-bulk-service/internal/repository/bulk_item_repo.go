@@ -21,13 +21,16 @@ func NewBulkItemRepository(db *mongo.Database) *BulkItemRepository {
 	}
 }
 
-// InsertMany inserts multiple BulkItem documents and sets default fields
+// InsertMany inserts multiple BulkItem documents and sets default fields.
+// Items rejected before processing (e.g. by ingest validation) keep their "failure" status
 func (r *BulkItemRepository) InsertMany(ctx context.Context, items []model.BulkItem) error {
 	now := time.Now()
 	for i := range items {
 		items[i].CreatedAt = now
 		items[i].UpdatedAt = now
-		items[i].Status = "pending"
+		if items[i].Status != "failure" {
+			items[i].Status = "pending"
+		}
 	}
 
 	docs := make([]interface{}, len(items))
@@ -153,3 +156,62 @@ func (r *BulkItemRepository) UpdateItemStatusWithError(
 	)
 	return err
 }
+
+// UpdateItemResult stores the final status of an item together with its error message, code and category
+func (r *BulkItemRepository) UpdateItemResult(
+	ctx context.Context,
+	itemID, status, errMsg, errCode, errCategory string,
+) error {
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateByID(
+		ctx,
+		objID,
+		map[string]interface{}{
+			"$set": map[string]interface{}{
+				"status":        status,
+				"errorMessage":  errMsg,
+				"errorCode":     errCode,
+				"errorCategory": errCategory,
+				"updatedAt":     time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// UpdateItemChanges records the before/after value of every field a bulk update set
+func (r *BulkItemRepository) UpdateItemChanges(ctx context.Context, itemID string, changes map[string]model.FieldChange) error {
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return fmt.Errorf("invalid itemID: %w", err)
+	}
+
+	update := map[string]interface{}{
+		"changes":   changes,
+		"updatedAt": time.Now(),
+	}
+
+	_, err = r.collection.UpdateByID(ctx, objID, map[string]interface{}{"$set": update})
+	return err
+}
+
+// UpdateItemTransition records the status found in inventory and the requested target status
+func (r *BulkItemRepository) UpdateItemTransition(ctx context.Context, itemID, fromStatus, toStatus string) error {
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return fmt.Errorf("invalid itemID: %w", err)
+	}
+
+	update := map[string]interface{}{
+		"fromStatus": fromStatus,
+		"toStatus":   toStatus,
+		"updatedAt":  time.Now(),
+	}
+
+	_, err = r.collection.UpdateByID(ctx, objID, map[string]interface{}{"$set": update})
+	return err
+}
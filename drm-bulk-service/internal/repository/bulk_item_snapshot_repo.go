@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BulkItemSnapshotRepository stores before-images of bulk update items
+type BulkItemSnapshotRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBulkItemSnapshotRepository(db *mongo.Database) *BulkItemSnapshotRepository {
+	return &BulkItemSnapshotRepository{
+		collection: db.Collection("bulk_item_snapshots"),
+	}
+}
+
+// Insert stores a snapshot and sets its generated ID
+func (r *BulkItemSnapshotRepository) Insert(ctx context.Context, snap *model.BulkItemSnapshot) error {
+	snap.CreatedAt = time.Now()
+
+	res, err := r.collection.InsertOne(ctx, snap)
+	if err != nil {
+		return err
+	}
+	snap.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindAppliedByBulkRequestID returns the snapshots of every item whose update was written
+func (r *BulkItemSnapshotRepository) FindAppliedByBulkRequestID(ctx context.Context, bulkReqID string) ([]model.BulkItemSnapshot, error) {
+	objID, err := primitive.ObjectIDFromHex(bulkReqID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bulkRequestID: %w", err)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"bulkRequestId": objID,
+		"appliedAt":     bson.M{"$exists": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var snaps []model.BulkItemSnapshot
+	if err := cursor.All(ctx, &snaps); err != nil {
+		return nil, err
+	}
+	return snaps, nil
+}
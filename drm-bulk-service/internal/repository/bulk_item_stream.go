@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the index reports use to read the items of a request in file order
+func (r *BulkItemRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "bulkRequestId", Value: 1},
+			{Key: "lineNumber", Value: 1},
+			{Key: "_id", Value: 1},
+		},
+	})
+	return err
+}
+
+// CursorByBulkRequestID streams the items of a request in file order (lineNumber, then
+// insertion order). With statuses only items in one of those statuses are returned.
+// The caller closes the cursor
+func (r *BulkItemRepository) CursorByBulkRequestID(
+	ctx context.Context,
+	bulkReqID string,
+	statuses []string,
+) (*mongo.Cursor, error) {
+	objectID, err := primitive.ObjectIDFromHex(bulkReqID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bulkRequestID: %w", err)
+	}
+
+	filter := bson.M{"bulkRequestId": objectID}
+	if len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "lineNumber", Value: 1}, {Key: "_id", Value: 1}}).
+		SetAllowDiskUse(true)
+
+	return r.collection.Find(ctx, filter, opts)
+}
+
+// Summarize counts the items of a request per status, error category and error code
+// and collects the updated field names in one aggregation, without loading the items
+func (r *BulkItemRepository) Summarize(ctx context.Context, bulkReqID string) (*model.BulkItemSummary, error) {
+	objectID, err := primitive.ObjectIDFromHex(bulkReqID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bulkRequestID: %w", err)
+	}
+
+	groupBy := func(field string) bson.A {
+		return bson.A{
+			bson.M{"$match": bson.M{field: bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$group": bson.M{"_id": "$" + field, "n": bson.M{"$sum": 1}}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"bulkRequestId": objectID}}},
+		{{Key: "$facet", Value: bson.M{
+			"status":   groupBy("status"),
+			"category": groupBy("errorCategory"),
+			"code":     groupBy("errorCode"),
+			"fields": bson.A{
+				bson.M{"$project": bson.M{"kv": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$updateFields", bson.M{}}}}}},
+				bson.M{"$unwind": "$kv"},
+				bson.M{"$group": bson.M{"_id": "$kv.k"}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"raw": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"width": bson.M{"$max": bson.M{"$size": bson.M{"$ifNull": bson.A{"$rawRow", bson.A{}}}}},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type bucket struct {
+		ID string `bson:"_id"`
+		N  int    `bson:"n"`
+	}
+	var facets []struct {
+		Status   []bucket `bson:"status"`
+		Category []bucket `bson:"category"`
+		Code     []bucket `bson:"code"`
+		Fields   []bucket `bson:"fields"`
+		Raw      []struct {
+			Width int `bson:"width"`
+		} `bson:"raw"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+
+	toMap := func(buckets []bucket) map[string]int {
+		m := map[string]int{}
+		for _, b := range buckets {
+			m[b.ID] = b.N
+		}
+		return m
+	}
+
+	summary := &model.BulkItemSummary{
+		StatusCounts:    map[string]int{},
+		CategoryCounts:  map[string]int{},
+		ErrorCodeCounts: map[string]int{},
+	}
+	if len(facets) == 0 {
+		return summary, nil
+	}
+
+	f := facets[0]
+	summary.StatusCounts = toMap(f.Status)
+	summary.CategoryCounts = toMap(f.Category)
+	summary.ErrorCodeCounts = toMap(f.Code)
+	for _, n := range summary.StatusCounts {
+		summary.Total += n
+	}
+	for _, b := range f.Fields {
+		summary.UpdateFields = append(summary.UpdateFields, b.ID)
+	}
+	if len(f.Raw) > 0 {
+		summary.RawRowWidth = f.Raw[0].Width
+	}
+	return summary, nil
+}
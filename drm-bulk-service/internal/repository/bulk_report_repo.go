@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // BulkReportRepository handles database operations for bulk report documents
@@ -32,7 +33,17 @@ func (r *BulkReportRepository) Create(ctx context.Context, report *model.BulkRep
 	return err
 }
 
-// FindByRequestID retrieves a single report by converting a string ID to a Mongo ObjectID
+// EnsureIndexes makes (requestId, version) unique so concurrent regenerations cannot
+// store the same version twice
+func (r *BulkReportRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "requestId", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// FindByRequestID retrieves the latest report version of a request
 func (r *BulkReportRepository) FindByRequestID(
 	ctx context.Context,
 	reqID string,
@@ -40,10 +51,81 @@ func (r *BulkReportRepository) FindByRequestID(
 
 	var report model.BulkReport
 	objectID, _ := primitive.ObjectIDFromHex(reqID)
-	err := r.collection.FindOne(ctx, bson.M{"requestId": objectID}).Decode(&report)
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"requestId": objectID}, opts).Decode(&report)
 
 	if err != nil {
 		return nil, err
 	}
+	normalizeVersion(&report)
+	return &report, nil
+}
+
+// FindByRequestIDAndVersion retrieves one report version of a request
+func (r *BulkReportRepository) FindByRequestIDAndVersion(
+	ctx context.Context,
+	reqID string,
+	version int,
+) (*model.BulkReport, error) {
+	objectID, _ := primitive.ObjectIDFromHex(reqID)
+	filter := bson.M{"requestId": objectID, "version": version}
+	if version == 1 {
+		// reports written before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{1, nil}}
+	}
+
+	var report model.BulkReport
+	if err := r.collection.FindOne(ctx, filter).Decode(&report); err != nil {
+		return nil, err
+	}
+	normalizeVersion(&report)
 	return &report, nil
 }
+
+// FindAllByRequestID lists every report version of a request, oldest first
+func (r *BulkReportRepository) FindAllByRequestID(
+	ctx context.Context,
+	reqID string,
+) ([]model.BulkReport, error) {
+	objectID, _ := primitive.ObjectIDFromHex(reqID)
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"requestId": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []model.BulkReport
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		normalizeVersion(&reports[i])
+	}
+	return reports, nil
+}
+
+func normalizeVersion(report *model.BulkReport) {
+	if report.Version == 0 {
+		report.Version = 1
+	}
+}
+
+// AddFormat records the GridFS file of a report format unless one is already recorded.
+// It reports false when another request stored that format first
+func (r *BulkReportRepository) AddFormat(
+	ctx context.Context,
+	reportID primitive.ObjectID,
+	format string,
+	fileID primitive.ObjectID,
+) (bool, error) {
+	key := "formats." + format
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": reportID, key: bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{key: fileID, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
@@ -137,3 +137,19 @@ func (r *BulkRequestRepository) UpdateCounts(ctx context.Context, id string, pro
 	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": update})
 	return err
 }
+
+// UpdateStatusCounts implements BulkRequestUpdater
+func (r *BulkRequestRepository) UpdateStatusCounts(ctx context.Context, id string, counts map[string]int) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"statusCounts": counts,
+		"updatedAt":    time.Now(),
+	}
+
+	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": update})
+	return err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+===========================
+Retention (see worker.RetentionJanitor)
+===========================
+*/
+
+// FindItemsExpired returns finished requests last updated before `before` whose items are still stored
+func (r *BulkRequestRepository) FindItemsExpired(ctx context.Context, before time.Time, limit int64) ([]model.BulkRequest, error) {
+	filter := bson.M{
+		"status":        bson.M{"$in": bson.A{"completed", "failed"}},
+		"updatedAt":     bson.M{"$lt": before},
+		"itemsPurgedAt": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reqs []model.BulkRequest
+	if err := cursor.All(ctx, &reqs); err != nil {
+		return nil, err
+	}
+	return reqs, nil
+}
+
+// MarkItemsPurged records that the items of a request were deleted. updatedAt is left alone
+func (r *BulkRequestRepository) MarkItemsPurged(ctx context.Context, id string, at time.Time) error {
+	return r.markPurged(ctx, id, "itemsPurgedAt", at)
+}
+
+// MarkReportsPurged records that report versions of a request were deleted
+func (r *BulkRequestRepository) MarkReportsPurged(ctx context.Context, id string, at time.Time) error {
+	return r.markPurged(ctx, id, "reportsPurgedAt", at)
+}
+
+func (r *BulkRequestRepository) markPurged(ctx context.Context, id, field string, at time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": bson.M{field: at}})
+	return err
+}
+
+// DeleteByBulkRequestID removes all items of a request
+func (r *BulkItemRepository) DeleteByBulkRequestID(ctx context.Context, bulkReqID string) (int64, error) {
+	objID, err := primitive.ObjectIDFromHex(bulkReqID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bulkRequestID: %w", err)
+	}
+	res, err := r.collection.DeleteMany(ctx, bson.M{"bulkRequestId": objID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteByBulkRequestID removes all snapshots of a request; it can no longer be rolled back
+func (r *BulkItemSnapshotRepository) DeleteByBulkRequestID(ctx context.Context, bulkReqID string) (int64, error) {
+	objID, err := primitive.ObjectIDFromHex(bulkReqID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bulkRequestID: %w", err)
+	}
+	res, err := r.collection.DeleteMany(ctx, bson.M{"bulkRequestId": objID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// FindCreatedBefore returns report versions created before `before`, oldest first
+func (r *BulkReportRepository) FindCreatedBefore(ctx context.Context, before time.Time, limit int64) ([]model.BulkReport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"createdAt": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []model.BulkReport
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Delete removes one report document (its GridFS files are removed by report.Service.Delete)
+func (r *BulkReportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindFinishedBefore returns export jobs that completed or failed before `before`, oldest first.
+// Jobs still running have no completedAt and are never returned
+func (r *ExportJobRepository) FindFinishedBefore(ctx context.Context, before time.Time, limit int64) ([]model.ExportJob, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "completedAt", Value: 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"completedAt": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []model.ExportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Delete removes one export job document (its GridFS file is removed by export.Service.DeleteFile)
+func (r *ExportJobRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "go.mongodb.org/mongo-driver/mongo/options"
+
+// NumericCollation compares digit sequences as numbers, so "9" < "10000" for
+// range filters and sorts on numeric identifier values (MSISDN, IMSI, ...)
+var NumericCollation = &options.Collation{Locale: "en", NumericOrdering: true}
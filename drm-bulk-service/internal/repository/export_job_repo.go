@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ExportJobRepository handles database operations for asynchronous export jobs
+type ExportJobRepository struct {
+	collection *mongo.Collection
+}
+
+// NewExportJobRepository initializes the repository with the bulk_exports collection
+func NewExportJobRepository(db *mongo.Database) *ExportJobRepository {
+	return &ExportJobRepository{
+		collection: db.Collection("bulk_exports"),
+	}
+}
+
+// Insert creates a new pending ExportJob and sets its generated ID
+func (r *ExportJobRepository) Insert(ctx context.Context, job *model.ExportJob) error {
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.Status = "pending"
+
+	res, err := r.collection.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	job.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByID retrieves one ExportJob
+func (r *ExportJobRepository) GetByID(ctx context.Context, jobID string) (*model.ExportJob, error) {
+	var job model.ExportJob
+	objID, _ := primitive.ObjectIDFromHex(jobID)
+	err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&job)
+	return &job, err
+}
+
+// UpdateStatus implements worker.ExportJobUpdater
+func (r *ExportJobRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": bson.M{
+		"status":    status,
+		"updatedAt": time.Now(),
+	}})
+	return err
+}
+
+// Complete implements worker.ExportJobUpdater: records the stored file and marks the job completed
+func (r *ExportJobRepository) Complete(ctx context.Context, id string, fileID primitive.ObjectID, fileName string, rows int) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": bson.M{
+		"status":      "completed",
+		"fileId":      fileID,
+		"fileName":    fileName,
+		"rowCount":    rows,
+		"completedAt": now,
+		"updatedAt":   now,
+	}})
+	return err
+}
+
+// Fail implements worker.ExportJobUpdater: marks the job failed with its error
+func (r *ExportJobRepository) Fail(ctx context.Context, id string, errMsg string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = r.collection.UpdateByID(ctx, objID, bson.M{"$set": bson.M{
+		"status":       "failed",
+		"errorMessage": errMsg,
+		"completedAt":  now,
+		"updatedAt":    now,
+	}})
+	return err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValuePatternRegex turns an allocation pattern such as 8007xxxxx (x = any digit)
+// into an anchored regular expression
+func ValuePatternRegex(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range pattern {
+		switch {
+		case c == 'x' || c == 'X':
+			b.WriteString(`\d`)
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		default:
+			return "", fmt.Errorf("invalid valuePattern %q: only digits and x are allowed", pattern)
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// EnsureAllocationIndexes creates the indexes Claim picks the lowest Available value
+// with: one plain and one with NumericCollation, since a query only uses an index
+// whose collation matches its own
+func (r *InventoryLogicalRepository) EnsureAllocationIndexes(ctx context.Context) error {
+	keys := bson.D{
+		{Key: "type", Value: 1},
+		{Key: "resourceStatus", Value: 1},
+		{Key: "value", Value: 1},
+	}
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: keys, Options: options.Index().SetName("allocation_value")},
+		{Keys: keys, Options: options.Index().SetName("allocation_value_numeric").SetCollation(NumericCollation)},
+	})
+	return err
+}
+
+// Claim atomically moves one Available logical resource matching criteria to status "to"
+// and marks it with the allocation. It returns ErrResourceNotFound when nothing matches,
+// so concurrent allocations never receive the same resource
+func (r *InventoryLogicalRepository) Claim(
+	ctx context.Context,
+	criteria model.AllocationCriteria,
+	to string,
+	alloc model.Allocation,
+	party *model.RelatedParty,
+) (*model.InventoryResource, error) {
+	filter := bson.M{"resourceStatus": "Available"}
+	if criteria.Type != "" {
+		filter["type"] = criteria.Type
+	}
+	if criteria.Category != "" {
+		filter["$or"] = bson.A{
+			bson.M{"category": criteria.Category},
+			bson.M{"resourceCharacteristic": bson.M{"$elemMatch": bson.M{
+				"code":  "MobileClass",
+				"value": criteria.Category,
+			}}},
+		}
+	}
+	if criteria.ValuePattern != "" {
+		expr, err := ValuePatternRegex(criteria.ValuePattern)
+		if err != nil {
+			return nil, err
+		}
+		filter["value"] = bson.M{"$regex": expr}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"resourceStatus": to,
+		"allocation":     alloc,
+		"updatedAt":      alloc.AllocatedAt,
+	}}
+	if party != nil {
+		marked := *party
+		marked.BulkRequestID = alloc.BulkRequestID
+		update["$push"] = bson.M{"relatedParty": marked}
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "value", Value: 1}}).
+		SetReturnDocument(options.After)
+	if criteria.NumericOrder {
+		opts.SetCollation(NumericCollation)
+	}
+
+	var res model.InventoryResource
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: no Available %s matches the allocation criteria", ErrResourceNotFound, criteria.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim %s failed: %w", r.collection.Name(), err)
+	}
+	return &res, nil
+}
+
+// ReleaseClaim returns a resource claimed by the given allocation request to Available,
+// pulling only the party entry the claim pushed
+func (r *InventoryLogicalRepository) ReleaseClaim(ctx context.Context, id, to, bulkRequestID string) error {
+	filter, err := statusGuard(id, to)
+	if err != nil {
+		return err
+	}
+	filter["allocation.bulkRequestId"] = bulkRequestID
+
+	update := bson.M{
+		"$set":   bson.M{"resourceStatus": "Available", "updatedAt": time.Now()},
+		"$unset": bson.M{"allocation": ""},
+		"$pull":  bson.M{"relatedParty": bson.M{"bulkRequestId": bulkRequestID}},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("release claim on %s failed: %w", r.collection.Name(), err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s id=%s no longer claimed by %s", ErrStatusChanged, r.collection.Name(), id, bulkRequestID)
+	}
+	return nil
+}
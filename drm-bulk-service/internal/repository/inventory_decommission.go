@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FindResource loads the identifying fields of a logical resource by (type, value)
+func (r *InventoryLogicalRepository) FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error) {
+	return findResource(ctx, r.collection, resourceType, value)
+}
+
+// Retire marks a logical resource Retired and stamps its end-of-life dates
+func (r *InventoryLogicalRepository) Retire(ctx context.Context, id, from string, endDate, recycleDate time.Time) error {
+	return retireResource(ctx, r.collection, id, from, endDate, recycleDate)
+}
+
+// Delete removes a logical resource, provided its status is still "from"
+func (r *InventoryLogicalRepository) Delete(ctx context.Context, id, from string) error {
+	return deleteResource(ctx, r.collection, id, from)
+}
+
+// DeleteByID removes a logical resource regardless of its status (compensation of a create)
+func (r *InventoryLogicalRepository) DeleteByID(ctx context.Context, id string) error {
+	return deleteByID(ctx, r.collection, id)
+}
+
+// FindResource loads the identifying fields of a physical resource by (type, value)
+func (r *InventoryPhysicalRepository) FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error) {
+	return findResource(ctx, r.collection, resourceType, value)
+}
+
+// Retire marks a physical resource Retired and stamps its end-of-life dates
+func (r *InventoryPhysicalRepository) Retire(ctx context.Context, id, from string, endDate, recycleDate time.Time) error {
+	return retireResource(ctx, r.collection, id, from, endDate, recycleDate)
+}
+
+// Delete removes a physical resource, provided its status is still "from"
+func (r *InventoryPhysicalRepository) Delete(ctx context.Context, id, from string) error {
+	return deleteResource(ctx, r.collection, id, from)
+}
+
+// DeleteByID removes a physical resource regardless of its status (compensation of a create)
+func (r *InventoryPhysicalRepository) DeleteByID(ctx context.Context, id string) error {
+	return deleteByID(ctx, r.collection, id)
+}
+
+func findResource(ctx context.Context, coll *mongo.Collection, resourceType, value string) (*model.InventoryResource, error) {
+	if value == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+
+	var res model.InventoryResource
+	err := coll.FindOne(ctx, resourceFilter(resourceType, value)).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: %s type=%s value=%s", ErrResourceNotFound, coll.Name(), resourceType, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load %s failed: %w", coll.Name(), err)
+	}
+	return &res, nil
+}
+
+// statusGuard matches a document by id only while it still has the status read earlier
+func statusGuard(id, from string) (bson.M, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource id: %w", err)
+	}
+
+	filter := bson.M{"_id": objID}
+	if from == "" {
+		filter["resourceStatus"] = bson.M{"$in": bson.A{nil, ""}}
+	} else {
+		filter["resourceStatus"] = from
+	}
+	return filter, nil
+}
+
+func retireResource(ctx context.Context, coll *mongo.Collection, id, from string, endDate, recycleDate time.Time) error {
+	filter, err := statusGuard(id, from)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"resourceStatus":      "Retired",
+		"endOperatingDate":    endDate,
+		"resourceRecycleDate": recycleDate,
+		"updatedAt":           time.Now(),
+	}}
+
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("retire %s failed: %w", coll.Name(), err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s id=%s expected %q", ErrStatusChanged, coll.Name(), id, from)
+	}
+	return nil
+}
+
+func deleteResource(ctx context.Context, coll *mongo.Collection, id, from string) error {
+	filter, err := statusGuard(id, from)
+	if err != nil {
+		return err
+	}
+
+	res, err := coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("delete %s failed: %w", coll.Name(), err)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s id=%s expected %q", ErrStatusChanged, coll.Name(), id, from)
+	}
+	return nil
+}
+
+func deleteByID(ctx context.Context, coll *mongo.Collection, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid resource id: %w", err)
+	}
+
+	if _, err := coll.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+		return fmt.Errorf("delete %s failed: %w", coll.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrConflict is returned when the inventory document exists but no longer
+// matches the values the caller expected (optimistic concurrency guard)
+var ErrConflict = errors.New("conflict")
+
+// ErrInvalidValue is returned when an update or expected value cannot be stored
+// in the field's type (e.g. a date that does not parse)
+var ErrInvalidValue = errors.New("invalid value")
+
+// inventoryDateFields are stored as BSON dates, so expected values must be parsed
+var inventoryDateFields = map[string]bool{
+	"createdAt":           true,
+	"updatedAt":           true,
+	"startOperatingDate":  true,
+	"endOperatingDate":    true,
+	"resourceRecycleDate": true,
+}
+
+// updatableFields are the inventory fields a bulk update may set. Lifecycle state
+// (resourceStatus) changes through the status operation and the timestamps are
+// maintained by the service, so neither can be written from an update file
+var updatableFields = map[string]bool{
+	"name":                true,
+	"description":         true,
+	"startOperatingDate":  true,
+	"endOperatingDate":    true,
+	"resourceRecycleDate": true,
+}
+
+// CheckUpdateField reports whether a bulk update may set field
+func CheckUpdateField(field string) error {
+	switch {
+	case field == "_id" || strings.HasPrefix(field, "$"):
+		return fmt.Errorf("field %q cannot be updated", field)
+	case field == "resourceStatus":
+		return fmt.Errorf("field %q cannot be updated, use the status operation", field)
+	case field == "createdAt" || field == "updatedAt":
+		return fmt.Errorf("field %q is maintained by the service", field)
+	case !updatableFields[field]:
+		return fmt.Errorf("field %q is not updatable", field)
+	}
+	return nil
+}
+
+// updateValues converts the values of a bulk field update to their stored types.
+// Date fields are parsed like expected values; an empty date clears the field
+func updateValues(fields map[string]string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if err := CheckUpdateField(k); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+
+		switch {
+		case inventoryDateFields[k] && v == "":
+			values[k] = nil
+		case inventoryDateFields[k]:
+			t, err := parseExpectedTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s %q: %v", ErrInvalidValue, k, v, err)
+			}
+			values[k] = t
+		default:
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// setFieldsUpdate builds the update of a bulk field update as a pipeline that only moves
+// updatedAt when at least one value actually changes, so an update that finds every
+// value already set leaves the document untouched
+func setFieldsUpdate(values map[string]interface{}, now time.Time) mongo.Pipeline {
+	changed := bson.A{}
+	set := bson.M{}
+	for k, v := range values {
+		changed = append(changed, bson.M{"$ne": bson.A{"$" + k, bson.M{"$literal": v}}})
+		set[k] = bson.M{"$literal": v}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"updatedAt": bson.M{"$cond": bson.A{bson.M{"$or": changed}, now, "$updatedAt"}},
+		}}},
+		{{Key: "$set", Value: set}},
+	}
+}
+
+// applyExpected adds the expected field values to an update filter.
+// An empty expected value matches a missing or empty field
+func applyExpected(filter bson.M, expected map[string]string) error {
+	for field, want := range expected {
+		if want == "" {
+			filter[field] = bson.M{"$in": bson.A{nil, ""}}
+			continue
+		}
+		if !inventoryDateFields[field] {
+			filter[field] = want
+			continue
+		}
+
+		t, err := parseExpectedTime(want)
+		if err != nil {
+			return fmt.Errorf("%w: expected %s %q: %v", ErrInvalidValue, field, want, err)
+		}
+		filter[field] = t
+	}
+	return nil
+}
+
+// parseExpectedTime accepts RFC3339 timestamps and epoch milliseconds (as exported)
+func parseExpectedTime(v string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Mongo stores dates with millisecond precision
+	return t.Truncate(time.Millisecond), nil
+}
+
+// conflictOrMissing explains why a guarded update matched nothing: the document is
+// either gone (ErrResourceNotFound) or changed since the caller's read (ErrConflict)
+func conflictOrMissing(ctx context.Context, coll *mongo.Collection, base bson.M, expected map[string]string) error {
+	n, err := coll.CountDocuments(ctx, base)
+	if err != nil {
+		return fmt.Errorf("load %s failed: %w", coll.Name(), err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s type=%v value=%v", ErrResourceNotFound, coll.Name(), base["type"], base["value"])
+	}
+	return fmt.Errorf("%w: %s value=%v no longer matches expected %v", ErrConflict, coll.Name(), base["value"], expected)
+}
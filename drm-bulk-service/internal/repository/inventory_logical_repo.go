@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
+
+	"drm-bulk-service/internal/model"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -24,47 +25,51 @@ func NewInventoryLogicalRepository(db *mongo.Database) *InventoryLogicalReposito
 
 // UpdateByTypeAndValue updates one logical resource by (type, value)
 // Example: fields["name"] = "Customer A Router"
+// expected (optional) guards the update: e.g. expected["resourceStatus"] = "Available"
+// makes it return ErrConflict if the document changed since it was exported
 func (r *InventoryLogicalRepository) UpdateByTypeAndValue(
 	ctx context.Context,
 	resourceType, value string,
 	fields map[string]string,
-) error {
+	expected map[string]string,
+) (model.InventoryUpdateResult, error) {
 
 	if resourceType == "" || value == "" {
-		return fmt.Errorf("resourceType and value are required")
+		return model.InventoryUpdateResult{}, fmt.Errorf("resourceType and value are required")
 	}
 	if len(fields) == 0 {
 		// nothing to update – not an error
 		log.Printf("[logicalresources] skip update: empty fields for type=%q value=%q", resourceType, value)
-		return nil
+		return model.InventoryUpdateResult{}, nil
 	}
 
 	filter := bson.M{
 		"type":  resourceType,
 		"value": value,
 	}
-
-	set := bson.M{
-		"updatedAt": time.Now(),
+	base := bson.M{
+		"type":  resourceType,
+		"value": value,
 	}
-	for k, v := range fields {
-		set[k] = v
+	if err := applyExpected(filter, expected); err != nil {
+		return model.InventoryUpdateResult{}, err
 	}
 
-	update := bson.M{"$set": set}
-
-	log.Printf("[logicalresources] UPDATE start: filter=%v set=%v", filter, set)
+	log.Printf("[logicalresources] UPDATE start: filter=%v set=%v", filter, fields)
 
-	res, err := r.collection.UpdateOne(ctx, filter, update)
+	result, found, err := applyFieldsUpdate(ctx, r.collection, filter, fields)
 	if err != nil {
 		log.Printf("[logicalresources] UPDATE error: %v", err)
-		return fmt.Errorf("update logicalresource failed: %w", err)
+		return model.InventoryUpdateResult{}, err
 	}
 
 	log.Printf("[logicalresources] UPDATE result: matched=%d modified=%d type=%q value=%q",
-		res.MatchedCount, res.ModifiedCount, resourceType, value)
+		result.Matched, result.Modified, resourceType, value)
 
-	if res.MatchedCount == 0 {
+	if !found && len(expected) > 0 {
+		return model.InventoryUpdateResult{}, conflictOrMissing(ctx, r.collection, base, expected)
+	}
+	if !found {
 		// Extra debug: see if document exists at least by value
 		var byValue bson.M
 		err2 := r.collection.FindOne(ctx, bson.M{"value": value}).Decode(&byValue)
@@ -74,17 +79,17 @@ func (r *InventoryLogicalRepository) UpdateByTypeAndValue(
 			log.Printf("[logicalresources] doc found by value=%q but filter type=%q didn't match: doc=%v",
 				value, resourceType, byValue)
 		}
-		return fmt.Errorf("no logicalresource found for type=%s value=%s", resourceType, value)
+		return model.InventoryUpdateResult{}, fmt.Errorf("no logicalresource found for type=%s value=%s", resourceType, value)
 	}
 
 	// log the updated document when a match happened
 	var doc bson.M
-	if err := r.collection.FindOne(ctx, filter).Decode(&doc); err == nil {
+	if err := r.collection.FindOne(ctx, base).Decode(&doc); err == nil {
 		log.Printf("[logicalresources] UPDATED DOC: %v", doc)
 	} else {
 		log.Printf("[logicalresources] failed to load updated doc for type=%q value=%q: %v",
 			resourceType, value, err)
 	}
 
-	return nil
+	return result, nil
 }
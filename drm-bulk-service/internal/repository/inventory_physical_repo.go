@@ -3,7 +3,8 @@ package repository
 import (
 	"context"
 	"fmt"
-	"time"
+
+	"drm-bulk-service/internal/model"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,36 +21,43 @@ func NewInventoryPhysicalRepository(db *mongo.Database) *InventoryPhysicalReposi
 	}
 }
 
+// UpdateByTypeAndValue updates one physical resource by (type, value);
+// see InventoryLogicalRepository.UpdateByTypeAndValue for expected
 func (r *InventoryPhysicalRepository) UpdateByTypeAndValue(
 	ctx context.Context,
 	resourceType, value string,
 	fields map[string]string,
-) error {
+	expected map[string]string,
+) (model.InventoryUpdateResult, error) {
 
 	if resourceType == "" || value == "" {
-		return fmt.Errorf("resourceType and value are required")
+		return model.InventoryUpdateResult{}, fmt.Errorf("resourceType and value are required")
 	}
 	if len(fields) == 0 {
-		return nil
+		return model.InventoryUpdateResult{}, nil
 	}
 
 	filter := bson.M{
 		"type":  resourceType,
 		"value": value,
 	}
-
-	set := bson.M{"updatedAt": time.Now()}
-	for k, v := range fields {
-		set[k] = v
+	base := bson.M{
+		"type":  resourceType,
+		"value": value,
+	}
+	if err := applyExpected(filter, expected); err != nil {
+		return model.InventoryUpdateResult{}, err
 	}
-	update := bson.M{"$set": set}
 
-	res, err := r.collection.UpdateOne(ctx, filter, update)
+	result, found, err := applyFieldsUpdate(ctx, r.collection, filter, fields)
 	if err != nil {
-		return fmt.Errorf("update physicalresource failed: %w", err)
+		return model.InventoryUpdateResult{}, err
+	}
+	if !found && len(expected) > 0 {
+		return model.InventoryUpdateResult{}, conflictOrMissing(ctx, r.collection, base, expected)
 	}
-	if res.MatchedCount == 0 {
-		return fmt.Errorf("no physicalresource found for type=%s value=%s", resourceType, value)
+	if !found {
+		return model.InventoryUpdateResult{}, fmt.Errorf("no physicalresource found for type=%s value=%s", resourceType, value)
 	}
-	return nil
+	return result, nil
 }
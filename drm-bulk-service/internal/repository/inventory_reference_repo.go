@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InventoryReferenceRepository answers "does any resource point at this one?"
+// across both the logicalresources and physicalresources collections
+type InventoryReferenceRepository struct {
+	collections []*mongo.Collection
+}
+
+func NewInventoryReferenceRepository(db *mongo.Database) *InventoryReferenceRepository {
+	return &InventoryReferenceRepository{
+		collections: []*mongo.Collection{
+			db.Collection("logicalresources"),
+			db.Collection("physicalresources"),
+		},
+	}
+}
+
+// IsReferenced reports whether another resource lists id in its
+// resourceRelationship or bundledResources
+func (r *InventoryReferenceRepository) IsReferenced(ctx context.Context, id string) (bool, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"resourceRelationship.resource.id": id},
+		bson.M{"bundledResources.id": id},
+	}}
+	if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+		// a resource pointing at itself does not block it
+		filter["_id"] = bson.M{"$ne": objID}
+	}
+
+	for _, coll := range r.collections {
+		n, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return false, fmt.Errorf("reference lookup in %s failed: %w", coll.Name(), err)
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
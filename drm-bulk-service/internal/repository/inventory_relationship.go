@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrRelationshipExists is returned when the same relationship to the same target is already attached
+var ErrRelationshipExists = errors.New("relationship already exists")
+
+// AddRelationship attaches a resourceRelationship to the logical resource with the given id
+func (r *InventoryLogicalRepository) AddRelationship(ctx context.Context, id string, rel model.ResourceRelationship) error {
+	return addRelationship(ctx, r.collection, id, rel)
+}
+
+// AddRelationship attaches a resourceRelationship to the physical resource with the given id
+func (r *InventoryPhysicalRepository) AddRelationship(ctx context.Context, id string, rel model.ResourceRelationship) error {
+	return addRelationship(ctx, r.collection, id, rel)
+}
+
+// addRelationship pushes rel unless a relationship of the same type to the same
+// resource exists. A "bundle" also lists the target in bundledResources
+func addRelationship(ctx context.Context, coll *mongo.Collection, id string, rel model.ResourceRelationship) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid resource id: %w", err)
+	}
+
+	filter := bson.M{
+		"_id": objID,
+		"resourceRelationship": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"relationshipType": rel.RelationshipType,
+			"resource.id":      rel.Resource.ID,
+		}}},
+	}
+
+	update := bson.M{
+		"$push": bson.M{"resourceRelationship": rel},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	if rel.RelationshipType == "bundle" {
+		update["$addToSet"] = bson.M{"bundledResources": bson.M{
+			"id":       rel.Resource.ID,
+			"type":     rel.Resource.Type,
+			"baseType": rel.Resource.BaseType,
+		}}
+		update["$set"].(bson.M)["isBundle"] = true
+	}
+
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("add relationship on %s failed: %w", coll.Name(), err)
+	}
+	if res.MatchedCount == 0 {
+		n, err := coll.CountDocuments(ctx, bson.M{"_id": objID})
+		if err != nil {
+			return fmt.Errorf("load %s failed: %w", coll.Name(), err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %s id=%s", ErrResourceNotFound, coll.Name(), id)
+		}
+		return fmt.Errorf("%w: %s -> %s", ErrRelationshipExists, rel.RelationshipType, rel.Resource.ID)
+	}
+	return nil
+}
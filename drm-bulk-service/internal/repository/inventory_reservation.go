@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reserve moves a logical resource from "from" to Reserved for a party until the reservation expires
+func (r *InventoryLogicalRepository) Reserve(ctx context.Context, id, from string, res model.Reservation) error {
+	return reserveResource(ctx, r.collection, id, from, res)
+}
+
+// FindExpiredReservations lists Reserved logical resources whose reservation expired before now
+func (r *InventoryLogicalRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int64) ([]model.InventoryResource, error) {
+	return findExpiredReservations(ctx, r.collection, now, limit)
+}
+
+// ReleaseReservation returns an expired reserved logical resource to Available
+func (r *InventoryLogicalRepository) ReleaseReservation(ctx context.Context, res model.InventoryResource, now time.Time) error {
+	return releaseReservation(ctx, r.collection, res, now)
+}
+
+// Reserve moves a physical resource from "from" to Reserved for a party until the reservation expires
+func (r *InventoryPhysicalRepository) Reserve(ctx context.Context, id, from string, res model.Reservation) error {
+	return reserveResource(ctx, r.collection, id, from, res)
+}
+
+// FindExpiredReservations lists Reserved physical resources whose reservation expired before now
+func (r *InventoryPhysicalRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int64) ([]model.InventoryResource, error) {
+	return findExpiredReservations(ctx, r.collection, now, limit)
+}
+
+// ReleaseReservation returns an expired reserved physical resource to Available
+func (r *InventoryPhysicalRepository) ReleaseReservation(ctx context.Context, res model.InventoryResource, now time.Time) error {
+	return releaseReservation(ctx, r.collection, res, now)
+}
+
+func reserveResource(ctx context.Context, coll *mongo.Collection, id, from string, res model.Reservation) error {
+	filter, err := statusGuard(id, from)
+	if err != nil {
+		return err
+	}
+
+	party := res.Party
+	party.BulkRequestID = res.BulkRequestID
+
+	update := bson.M{
+		"$set": bson.M{
+			"resourceStatus": "Reserved",
+			"reservation":    res,
+			"updatedAt":      time.Now(),
+		},
+		"$push": bson.M{"relatedParty": party},
+	}
+
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("reserve %s failed: %w", coll.Name(), err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s id=%s expected %q", ErrStatusChanged, coll.Name(), id, from)
+	}
+	return nil
+}
+
+func findExpiredReservations(ctx context.Context, coll *mongo.Collection, now time.Time, limit int64) ([]model.InventoryResource, error) {
+	filter := bson.M{
+		"resourceStatus":        "Reserved",
+		"reservation.expiresAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "reservation.expiresAt", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find expired reservations in %s failed: %w", coll.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var out []model.InventoryResource
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// releaseReservation only releases a document that is still Reserved under the same,
+// expired reservation, so a renewed or consumed reservation is left alone. Only the
+// party entry the reservation pushed (marked with its bulkRequestId) is pulled
+func releaseReservation(ctx context.Context, coll *mongo.Collection, res model.InventoryResource, now time.Time) error {
+	if res.Reservation == nil {
+		return fmt.Errorf("resource %s has no reservation", res.Value)
+	}
+
+	filter := bson.M{
+		"_id":                   res.ID,
+		"resourceStatus":        "Reserved",
+		"reservation.expiresAt": res.Reservation.ExpiresAt,
+	}
+	update := bson.M{
+		"$set":   bson.M{"resourceStatus": "Available", "updatedAt": now},
+		"$unset": bson.M{"reservation": ""},
+		"$pull": bson.M{"relatedParty": bson.M{
+			"name":          res.Reservation.Party.Name,
+			"role":          res.Reservation.Party.Role,
+			"bulkRequestId": res.Reservation.BulkRequestID,
+		}},
+	}
+
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("release %s failed: %w", coll.Name(), err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s id=%s reservation changed", ErrStatusChanged, coll.Name(), res.ID.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Restore writes a before-image back onto a logical resource, provided it was
+// not modified after appliedAt
+func (r *InventoryLogicalRepository) Restore(
+	ctx context.Context,
+	resourceType, value string,
+	before map[string]interface{},
+	missing []string,
+	appliedAt time.Time,
+) error {
+	return restoreFields(ctx, r.collection, resourceType, value, before, missing, appliedAt)
+}
+
+// Restore writes a before-image back onto a physical resource, provided it was
+// not modified after appliedAt
+func (r *InventoryPhysicalRepository) Restore(
+	ctx context.Context,
+	resourceType, value string,
+	before map[string]interface{},
+	missing []string,
+	appliedAt time.Time,
+) error {
+	return restoreFields(ctx, r.collection, resourceType, value, before, missing, appliedAt)
+}
+
+// applyFieldsUpdate sets fields on the document matching filter and takes the
+// before-image of those fields from the same findOneAndUpdate, so a concurrent
+// write cannot slip in between reading the previous values and overwriting them.
+// found is false when no document matched
+func applyFieldsUpdate(
+	ctx context.Context,
+	coll *mongo.Collection,
+	filter bson.M,
+	fields map[string]string,
+) (model.InventoryUpdateResult, bool, error) {
+	values, err := updateValues(fields)
+	if err != nil {
+		return model.InventoryUpdateResult{}, false, err
+	}
+
+	// Mongo keeps milliseconds; truncate so the result matches the stored value
+	now := time.Now().Truncate(time.Millisecond)
+
+	projection := bson.M{}
+	for f := range values {
+		projection[f] = 1
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.Before).
+		SetProjection(projection)
+
+	var doc bson.M
+	err = coll.FindOneAndUpdate(ctx, filter, setFieldsUpdate(values, now), opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.InventoryUpdateResult{}, false, nil
+	}
+	if err != nil {
+		return model.InventoryUpdateResult{}, true, fmt.Errorf("update %s failed: %w", coll.Name(), err)
+	}
+
+	result := model.InventoryUpdateResult{Matched: 1, Before: map[string]interface{}{}}
+	names := make([]string, 0, len(values))
+	for f := range values {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	changed := false
+	for _, f := range names {
+		old, ok := doc[f]
+		if !ok {
+			result.Missing = append(result.Missing, f)
+		} else {
+			result.Before[f] = old
+		}
+		if !ok || !sameValue(old, values[f]) {
+			changed = true
+		}
+	}
+	if changed {
+		result.Modified = 1
+		result.UpdatedAt = now
+	}
+	return result, true, nil
+}
+
+// sameValue reports whether a stored value equals the value an update writes,
+// mirroring the $ne of setFieldsUpdate
+func sameValue(old, v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return old == nil
+	case time.Time:
+		d, ok := old.(primitive.DateTime)
+		return ok && d.Time().Equal(t)
+	case string:
+		s, ok := old.(string)
+		return ok && s == t
+	}
+	return false
+}
+
+func restoreFields(
+	ctx context.Context,
+	coll *mongo.Collection,
+	resourceType, value string,
+	before map[string]interface{},
+	missing []string,
+	appliedAt time.Time,
+) error {
+	base := resourceFilter(resourceType, value)
+	filter := resourceFilter(resourceType, value)
+	filter["updatedAt"] = appliedAt
+
+	set := bson.M{"updatedAt": time.Now()}
+	for k, v := range before {
+		set[k] = v
+	}
+	update := bson.M{"$set": set}
+	if len(missing) > 0 {
+		unset := bson.M{}
+		for _, f := range missing {
+			unset[f] = ""
+		}
+		update["$unset"] = unset
+	}
+
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("restore %s failed: %w", coll.Name(), err)
+	}
+	if res.MatchedCount == 0 {
+		return conflictOrMissing(ctx, coll, base, map[string]string{
+			"updatedAt": appliedAt.UTC().Format(time.RFC3339Nano),
+		})
+	}
+	return nil
+}
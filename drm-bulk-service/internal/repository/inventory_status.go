@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrResourceNotFound is returned when no inventory document matches (type, value)
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrStatusChanged is returned when the resourceStatus changed between read and write
+var ErrStatusChanged = errors.New("resource status changed concurrently")
+
+// GetStatus returns the current resourceStatus of a logical resource
+func (r *InventoryLogicalRepository) GetStatus(ctx context.Context, resourceType, value string) (string, error) {
+	return findStatus(ctx, r.collection, resourceType, value)
+}
+
+// TransitionStatus moves a logical resource from one resourceStatus to another
+func (r *InventoryLogicalRepository) TransitionStatus(
+	ctx context.Context,
+	resourceType, value, from, to string,
+) error {
+	return transitionStatus(ctx, r.collection, resourceType, value, from, to)
+}
+
+// GetStatus returns the current resourceStatus of a physical resource
+func (r *InventoryPhysicalRepository) GetStatus(ctx context.Context, resourceType, value string) (string, error) {
+	return findStatus(ctx, r.collection, resourceType, value)
+}
+
+// TransitionStatus moves a physical resource from one resourceStatus to another
+func (r *InventoryPhysicalRepository) TransitionStatus(
+	ctx context.Context,
+	resourceType, value, from, to string,
+) error {
+	return transitionStatus(ctx, r.collection, resourceType, value, from, to)
+}
+
+// resourceFilter selects one inventory document by value and, if given, type
+func resourceFilter(resourceType, value string) bson.M {
+	filter := bson.M{"value": value}
+	if resourceType != "" {
+		filter["type"] = resourceType
+	}
+	return filter
+}
+
+func findStatus(ctx context.Context, coll *mongo.Collection, resourceType, value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("value is required")
+	}
+
+	var doc struct {
+		ResourceStatus string `bson:"resourceStatus"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"resourceStatus": 1})
+	err := coll.FindOne(ctx, resourceFilter(resourceType, value), opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", fmt.Errorf("%w: %s type=%s value=%s", ErrResourceNotFound, coll.Name(), resourceType, value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("load %s failed: %w", coll.Name(), err)
+	}
+	return doc.ResourceStatus, nil
+}
+
+// transitionStatus only writes when the document still has the status read earlier,
+// so a concurrent change cannot be silently overwritten
+func transitionStatus(ctx context.Context, coll *mongo.Collection, resourceType, value, from, to string) error {
+	filter := resourceFilter(resourceType, value)
+	if from == "" {
+		// documents created without a status have no resourceStatus field (or an empty one)
+		filter["resourceStatus"] = bson.M{"$in": bson.A{nil, ""}}
+	} else {
+		filter["resourceStatus"] = from
+	}
+
+	update := bson.M{"$set": bson.M{
+		"resourceStatus": to,
+		"updatedAt":      time.Now(),
+	}}
+
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("update %s status failed: %w", coll.Name(), err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s value=%s expected %q", ErrStatusChanged, coll.Name(), value, from)
+	}
+	return nil
+}
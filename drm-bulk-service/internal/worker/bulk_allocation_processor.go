@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+)
+
+// InventoryAllocator claims Available logical resources by criteria and can give them back
+// Concrete implementation: InventoryLogicalRepository
+type InventoryAllocator interface {
+	Claim(ctx context.Context, criteria model.AllocationCriteria, to string, alloc model.Allocation, party *model.RelatedParty) (*model.InventoryResource, error)
+	ReleaseClaim(ctx context.Context, id, to, bulkRequestID string) error
+}
+
+// AllocationProcessor picks Count Available resources matching the criteria and
+// moves them to ToStatus. Every resource is claimed with a single findOneAndUpdate,
+// so concurrent allocations never hand out the same resource.
+// Unless AllowPartial is set, an allocation that cannot be filled completely
+// releases what it claimed and reports those items as "rolled_back"
+type AllocationProcessor struct {
+	itemRepo  BulkItemCreator
+	reqRepo   BulkRequestCreator
+	reportSvc *report.Service
+	allocator InventoryAllocator
+}
+
+// AllocationSpec is one allocation order
+type AllocationSpec struct {
+	Criteria     model.AllocationCriteria
+	Count        int
+	ToStatus     string
+	Party        *model.RelatedParty
+	AllowPartial bool
+}
+
+func NewAllocationProcessor(
+	itemRepo BulkItemCreator,
+	reqRepo BulkRequestCreator,
+	reportSvc *report.Service,
+	allocator InventoryAllocator,
+) *AllocationProcessor {
+	return &AllocationProcessor{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		allocator: allocator,
+	}
+}
+
+func (p *AllocationProcessor) Process(ctx context.Context, req model.BulkRequest, spec AllocationSpec) {
+	start := time.Now()
+	log.Printf("BULK ALLOCATE PROCESSOR STARTED: count=%d type=%s category=%s pattern=%s\n",
+		spec.Count, spec.Criteria.Type, spec.Criteria.Category, spec.Criteria.ValuePattern)
+
+	_ = p.reqRepo.UpdateStatus(ctx, req.ID.Hex(), "processing")
+
+	alloc := model.Allocation{BulkRequestID: req.ID.Hex(), AllocatedAt: time.Now().UTC().Truncate(time.Millisecond)}
+
+	// every claim gets its item right away, so a claimed resource is never left
+	// without a record of it; a claim whose item cannot be saved is given back
+	var claimed []model.InventoryResource
+	var items []model.BulkItem
+	var shortfall error
+	for len(claimed) < spec.Count {
+		res, err := p.allocator.Claim(ctx, spec.Criteria, spec.ToStatus, alloc, spec.Party)
+		if err != nil {
+			shortfall = err
+			break
+		}
+		item, err := p.recordClaim(ctx, req, spec, *res)
+		if err != nil {
+			shortfall = err
+			break
+		}
+		claimed = append(claimed, *res)
+		items = append(items, item)
+	}
+	if shortfall != nil && !errors.Is(shortfall, repository.ErrResourceNotFound) {
+		log.Printf("[allocate] claim failed request=%s err=%v", req.ID.Hex(), shortfall)
+	}
+
+	// outcome per claimed resource id
+	outcomes := make(map[string]itemOutcome, len(claimed))
+	for _, res := range claimed {
+		outcomes[res.ID.Hex()] = itemOutcome{status: "success"}
+	}
+
+	if len(claimed) < spec.Count && !spec.AllowPartial {
+		cause := newItemError("ALLOCATION_INCOMPLETE", model.ErrorCategoryNotFound, "allocation incomplete: %d of %d resources claimed", len(claimed), spec.Count)
+		if shortfall != nil {
+			cause = fmt.Errorf("%w: %v", cause, shortfall)
+		}
+		for _, res := range claimed {
+			if err := p.allocator.ReleaseClaim(ctx, res.ID.Hex(), spec.ToStatus, req.ID.Hex()); err != nil {
+				outcomes[res.ID.Hex()] = itemOutcome{status: "failure", err: fmt.Errorf("%w; release failed: %v", cause, err)}
+				continue
+			}
+			outcomes[res.ID.Hex()] = itemOutcome{status: "rolled_back", err: cause}
+		}
+	}
+
+	if len(claimed) == 0 {
+		log.Printf("BULK ALLOCATE PROCESSOR FINISHED: request=%s nothing allocated err=%v", req.ID.Hex(), shortfall)
+		_ = p.reqRepo.UpdateStatus(ctx, req.ID.Hex(), "failed")
+		_ = p.reportSvc.Finalize(ctx, req)
+		return
+	}
+
+	byItem := make(map[string]itemOutcome, len(claimed))
+	_ = p.reqRepo.UpdateTotalCount(ctx, req.ID.Hex(), len(items))
+	for i, item := range items {
+		byItem[item.ID.Hex()] = outcomes[claimed[i].ID.Hex()]
+	}
+
+	// claiming already happened; the pool only records each outcome on its item
+	counts := runPool(ctx, "allocate", items, p.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		o := byItem[item.ID.Hex()]
+		return o.status, o.err
+	})
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK ALLOCATE PROCESSOR FINISHED: requested=%d success=%d failure=%d duration=%s",
+		spec.Count, success, failure, time.Since(start))
+}
+
+// recordClaim saves the item for a resource that was just claimed. When the item
+// cannot be saved the claim is released again before the error is returned
+func (p *AllocationProcessor) recordClaim(ctx context.Context, req model.BulkRequest, spec AllocationSpec, res model.InventoryResource) (model.BulkItem, error) {
+	batch := []model.BulkItem{{
+		BulkRequestID: req.ID,
+		Value:         res.Value,
+		Type:          res.Type,
+		BaseType:      "LogicalResource",
+		Status:        "pending",
+		FromStatus:    lifecycle.StatusAvailable,
+		ToStatus:      spec.ToStatus,
+	}}
+	err := p.itemRepo.InsertMany(ctx, batch)
+	if err == nil {
+		return batch[0], nil
+	}
+
+	log.Printf("[allocate] failed to save item request=%s value=%s err=%v", req.ID.Hex(), res.Value, err)
+	if relErr := p.allocator.ReleaseClaim(ctx, res.ID.Hex(), spec.ToStatus, req.ID.Hex()); relErr != nil {
+		log.Printf("[allocate] failed to release claim request=%s value=%s err=%v", req.ID.Hex(), res.Value, relErr)
+		return model.BulkItem{}, fmt.Errorf("save item for %s: %w; release failed: %v", res.Value, err, relErr)
+	}
+	return model.BulkItem{}, fmt.Errorf("save item for %s: %w", res.Value, err)
+}
+
+type itemOutcome struct {
+	status string
+	err    error
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+)
+
+// InventoryDecommissioner retires or deletes inventory documents
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryDecommissioner interface {
+	FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error)
+	Retire(ctx context.Context, id, from string, endDate, recycleDate time.Time) error
+	Delete(ctx context.Context, id, from string) error
+}
+
+// ReferenceChecker reports whether another resource still points at a resource id
+type ReferenceChecker interface {
+	IsReferenced(ctx context.Context, id string) (bool, error)
+}
+
+// DecommissionProcessor executes bulk "retire" and "delete" requests.
+// Resources that are InUse or referenced by another resource are refused
+type DecommissionProcessor struct {
+	itemRepo  BulkStatusItemRepo
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+	lifecycle *lifecycle.StateMachine
+	refs      ReferenceChecker
+
+	logical  InventoryDecommissioner
+	physical InventoryDecommissioner
+
+	recycleAfter time.Duration
+}
+
+func NewDecommissionProcessor(
+	itemRepo BulkStatusItemRepo,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	sm *lifecycle.StateMachine,
+	refs ReferenceChecker,
+	logical InventoryDecommissioner,
+	physical InventoryDecommissioner,
+	recycleAfter time.Duration,
+) *DecommissionProcessor {
+	return &DecommissionProcessor{
+		itemRepo:     itemRepo,
+		reqRepo:      reqRepo,
+		reportSvc:    reportSvc,
+		lifecycle:    sm,
+		refs:         refs,
+		logical:      logical,
+		physical:     physical,
+		recycleAfter: recycleAfter,
+	}
+}
+
+// Process runs req.Operation ("retire" or "delete") for every item
+func (p *DecommissionProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+) {
+	start := time.Now()
+	log.Printf("BULK %s PROCESSOR STARTED: items=%d\n", req.Operation, len(items))
+
+	counts := runPool(ctx, req.Operation, items, p.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		return p.decommission(ctx, req.Operation, item)
+	})
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK %s PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
+		req.Operation, len(items), success, failure, time.Since(start))
+}
+
+func (p *DecommissionProcessor) decommission(ctx context.Context, operation string, item model.BulkItem) (string, error) {
+	var inv InventoryDecommissioner
+	switch item.BaseType {
+	case "LogicalResource":
+		inv = p.logical
+	case "PhysicalResource":
+		inv = p.physical
+	default:
+		return "failure", invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", item.BaseType)
+	}
+	if inv == nil {
+		return "failure", fmt.Errorf("no inventory decommissioner configured for %s", item.BaseType)
+	}
+
+	res, err := inv.FindResource(ctx, item.Type, item.Value)
+	if err != nil {
+		return "failure", err
+	}
+
+	if err := p.itemRepo.UpdateItemTransition(ctx, item.ID.Hex(), res.ResourceStatus, item.ToStatus); err != nil {
+		log.Printf("[%s] failed to record transition item=%s err=%v", operation, item.ID.Hex(), err)
+	}
+
+	// ---------- Safety checks ----------
+	if res.ResourceStatus == lifecycle.StatusInUse {
+		return "failure", newItemError("RESOURCE_IN_USE", model.ErrorCategoryConflict, "refused: resource %s is InUse", item.Value)
+	}
+
+	referenced, err := p.refs.IsReferenced(ctx, res.ID.Hex())
+	if err != nil {
+		return "failure", err
+	}
+	if referenced {
+		return "failure", newItemError("RESOURCE_REFERENCED", model.ErrorCategoryConflict, "refused: resource %s is referenced by another resource's relationship", item.Value)
+	}
+
+	switch operation {
+	case "retire":
+		if err := p.lifecycle.Validate(res.ResourceStatus, lifecycle.StatusRetired); err != nil {
+			return "failure", err
+		}
+		now := time.Now()
+		if err := inv.Retire(ctx, res.ID.Hex(), res.ResourceStatus, now, now.Add(p.recycleAfter)); err != nil {
+			return "failure", err
+		}
+
+	case "delete":
+		if err := inv.Delete(ctx, res.ID.Hex(), res.ResourceStatus); err != nil {
+			return "failure", err
+		}
+
+	default:
+		return "failure", invalidf("UNSUPPORTED_OPERATION", "unsupported operation: %s", operation)
+	}
+
+	return "success", nil
+}
@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+)
+
+// InventoryLinker resolves inventory documents and attaches relationships to them
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryLinker interface {
+	FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error)
+	AddRelationship(ctx context.Context, id string, rel model.ResourceRelationship) error
+}
+
+// LinkProcessor creates resourceRelationship entries (reliesOn, bundle, pool, ...)
+// between existing resources
+type LinkProcessor struct {
+	itemRepo  BulkItemUpdater
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+
+	logical  InventoryLinker
+	physical InventoryLinker
+}
+
+func NewLinkProcessor(
+	itemRepo BulkItemUpdater,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	logical InventoryLinker,
+	physical InventoryLinker,
+) *LinkProcessor {
+	return &LinkProcessor{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		logical:   logical,
+		physical:  physical,
+	}
+}
+
+func (p *LinkProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+) {
+	start := time.Now()
+	log.Printf("BULK LINK PROCESSOR STARTED: items=%d\n", len(items))
+
+	counts := runPool(ctx, "link", items, p.itemRepo, p.link)
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK LINK PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
+		len(items), success, failure, time.Since(start))
+}
+
+func (p *LinkProcessor) link(ctx context.Context, item model.BulkItem) (string, error) {
+	spec := item.Link
+	if spec == nil {
+		return "failure", invalidf("MISSING_FIELD", "missing link specification")
+	}
+	if !model.IsRelationshipType(spec.RelationshipType) {
+		return "failure", invalidf("UNSUPPORTED_RELATIONSHIP_TYPE", "unsupported relationshipType %q", spec.RelationshipType)
+	}
+
+	validFor, err := buildValidFor(spec.StartDateTime, spec.EndDateTime)
+	if err != nil {
+		return "failure", err
+	}
+
+	sourceSide, err := p.linkerFor(item.BaseType)
+	if err != nil {
+		return "failure", err
+	}
+	targetSide, err := p.linkerFor(spec.TargetBaseType)
+	if err != nil {
+		return "failure", err
+	}
+
+	source, err := resolve(ctx, sourceSide, "source", item.Type, item.Value)
+	if err != nil {
+		return "failure", err
+	}
+	target, err := resolve(ctx, targetSide, "target", spec.TargetType, spec.TargetValue)
+	if err != nil {
+		return "failure", err
+	}
+
+	rel := model.ResourceRelationship{
+		RelationshipType: spec.RelationshipType,
+		ValidFor:         validFor,
+		Resource: model.ResourceRef{
+			ID:       target.ID.Hex(),
+			Type:     target.Type,
+			BaseType: spec.TargetBaseType,
+		},
+	}
+	if err := sourceSide.AddRelationship(ctx, source.ID.Hex(), rel); err != nil {
+		return "failure", err
+	}
+	return "success", nil
+}
+
+func (p *LinkProcessor) linkerFor(baseType string) (InventoryLinker, error) {
+	switch baseType {
+	case "LogicalResource":
+		return p.logical, nil
+	case "PhysicalResource":
+		return p.physical, nil
+	default:
+		return nil, invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", baseType)
+	}
+}
+
+// resolve loads one end of a relationship; a missing resource is reported as an unresolved reference
+func resolve(ctx context.Context, linker InventoryLinker, side, resourceType, value string) (*model.InventoryResource, error) {
+	res, err := linker.FindResource(ctx, resourceType, value)
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, newItemError("UNRESOLVED_REFERENCE", model.ErrorCategoryNotFound, "unresolved %s reference: type=%s value=%s", side, resourceType, value)
+	}
+	return res, err
+}
+
+// buildValidFor parses the optional validity window of a relationship
+func buildValidFor(start, end string) (*model.ValidFor, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+
+	validFor := &model.ValidFor{}
+	if start != "" {
+		t, err := parseDateTime(start)
+		if err != nil {
+			return nil, invalidf("INVALID_DATE", "invalid startDateTime %q", start)
+		}
+		validFor.StartDateTime = &t
+	}
+	if end != "" {
+		t, err := parseDateTime(end)
+		if err != nil {
+			return nil, invalidf("INVALID_DATE", "invalid endDateTime %q", end)
+		}
+		validFor.EndDateTime = &t
+	}
+	if validFor.StartDateTime != nil && validFor.EndDateTime != nil &&
+		validFor.EndDateTime.Before(*validFor.StartDateTime) {
+		return nil, invalidf("INVALID_DATE_RANGE", "endDateTime %s is before startDateTime %s", end, start)
+	}
+	return validFor, nil
+}
+
+// parseDateTime accepts RFC3339 timestamps and plain dates (2006-01-02)
+func parseDateTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
@@ -2,9 +2,8 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	grpcclient "drm-bulk-service/internal/grpc"
@@ -25,6 +24,10 @@ type Processor struct {
 	itemRepo  BulkItemUpdater
 	reqRepo   BulkRequestUpdater
 	reportSvc *report.Service
+
+	// used to compensate rows that create more than one resource
+	logical  InventoryRemover
+	physical InventoryRemover
 }
 
 /*
@@ -33,12 +36,13 @@ Interfaces
 ===========================
 */
 type BulkItemUpdater interface {
-	UpdateItemStatusWithError(ctx context.Context, itemID, status, errMsg string) error
+	UpdateItemResult(ctx context.Context, itemID, status, errMsg, errCode, errCategory string) error
 }
 
 type BulkRequestUpdater interface {
 	UpdateStatus(ctx context.Context, id string, status string) error
 	UpdateCounts(ctx context.Context, id string, processed, success, failure int) error
+	UpdateStatusCounts(ctx context.Context, id string, counts map[string]int) error
 }
 
 /*
@@ -51,12 +55,16 @@ func NewProcessor(
 	itemRepo BulkItemUpdater,
 	reqRepo BulkRequestUpdater,
 	reportSvc *report.Service,
+	logical InventoryRemover,
+	physical InventoryRemover,
 ) *Processor {
 	return &Processor{
 		invClient: inv,
 		itemRepo:  itemRepo,
 		reqRepo:   reqRepo,
 		reportSvc: reportSvc,
+		logical:   logical,
+		physical:  physical,
 	}
 }
 
@@ -74,72 +82,8 @@ func (p *Processor) Process(
 
 	log.Printf("BULK PROCESSOR STARTED: items=%d\n", len(items))
 
-	const workerCount = 10
-
-	jobs := make(chan model.BulkItem)
-
-	var wg sync.WaitGroup
-	var successCount int32
-	var failureCount int32
-
-	// Start workers
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for item := range jobs {
-				status := "success"
-				errMsg := ""
-
-				if err := callInventory(p.invClient, item); err != nil {
-					status = "failure"
-					errMsg = err.Error()
-					log.Printf("[worker %d] inventory failed item=%s err=%v",
-						workerID, item.ID.Hex(), err)
-				}
-
-				if err := p.itemRepo.UpdateItemStatusWithError(
-					ctx,
-					item.ID.Hex(),
-					status,
-					errMsg,
-				); err != nil {
-					log.Printf("[worker %d] mongo update failed item=%s err=%v",
-						workerID, item.ID.Hex(), err)
-				}
-
-				if status == "success" {
-					atomic.AddInt32(&successCount, 1)
-				} else {
-					atomic.AddInt32(&failureCount, 1)
-				}
-			}
-		}(i + 1)
-	}
-
-	// Send jobs
-	go func() {
-		defer close(jobs)
-		for _, item := range items {
-			select {
-			case <-ctx.Done():
-				log.Println("context cancelled, stopping bulk processor")
-				return
-			case jobs <- item:
-			}
-		}
-	}()
-
-	// Wait for all workers to finish
-	wg.Wait()
-
-	success := int(atomic.LoadInt32(&successCount))
-	failure := int(atomic.LoadInt32(&failureCount))
-	processed := success + failure
-
-	_ = p.reqRepo.UpdateCounts(ctx, req.ID.Hex(), processed, success, failure)
-	_ = p.reqRepo.UpdateStatus(ctx, req.ID.Hex(), "completed")
-	_ = p.reportSvc.Finalize(ctx, req)
+	counts := runPool(ctx, "create", items, p.itemRepo, p.create)
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
 
 	duration := time.Since(start) // end timer
 	log.Printf(
@@ -148,12 +92,62 @@ func (p *Processor) Process(
 	)
 }
 
+/*
+===========================
+Create (one row = one unit of work)
+===========================
+*/
+func (p *Processor) create(ctx context.Context, item model.BulkItem) (string, error) {
+	if item.Companion == nil {
+		if _, err := callInventory(p.invClient, item); err != nil {
+			return "failure", err
+		}
+		return "success", nil
+	}
+
+	// A companion row must be able to remove its primary resource again,
+	// so the remover is resolved before anything is created
+	remover := p.removerFor(item.BaseType)
+	if remover == nil {
+		return "failure", fmt.Errorf("no inventory remover configured for %s", item.BaseType)
+	}
+
+	tx := &rowTx{}
+	id, err := callInventory(p.invClient, item)
+	if err != nil {
+		return tx.Fail(ctx, err)
+	}
+	id, err = tx.RecordCreate(ctx, remover, item.Type, itemValue(item), id)
+	if err != nil {
+		return tx.Fail(ctx, err)
+	}
+
+	// ---------- Companion resource (same unit of work) ----------
+	primary := model.ResourceRef{ID: id, Type: item.Type, BaseType: item.BaseType}
+	if _, err := callCompanion(p.invClient, *item.Companion, primary); err != nil {
+		return tx.Fail(ctx, fmt.Errorf("create %s %s: %w", item.Companion.Type, item.Companion.Value, err))
+	}
+	return "success", nil
+}
+
+func (p *Processor) removerFor(baseType string) InventoryRemover {
+	switch baseType {
+	case "LogicalResource":
+		return p.logical
+	case "PhysicalResource":
+		return p.physical
+	default:
+		return nil
+	}
+}
+
 /*
 ===========================
 Inventory Call
 ===========================
 */
-func callInventory(client *grpcclient.InventoryClient, item model.BulkItem) error {
+// callInventory creates the item's resource and returns the id Inventory reported
+func callInventory(client *grpcclient.InventoryClient, item model.BulkItem) (string, error) {
 	ctx, cancel := grpcclient.Context()
 	defer cancel()
 
@@ -170,18 +164,71 @@ func callInventory(client *grpcclient.InventoryClient, item model.BulkItem) erro
 	}
 
 	if item.BaseType == "LogicalResource" {
-		_, err := client.Logical.CreateLogicalResource(
+		resp, err := client.Logical.CreateLogicalResource(
 			ctx,
 			buildLogical(item, msisdn, mobileClass),
 		)
-		return err
+		return resp.GetId(), err
 	}
 
-	_, err := client.Physical.CreatePhysicalResource(
+	resp, err := client.Physical.CreatePhysicalResource(
 		ctx,
 		buildPhysical(item, msisdn, mobileClass),
 	)
-	return err
+	return resp.GetId(), err
+}
+
+// callCompanion creates a companion resource that reliesOn the primary resource
+func callCompanion(client *grpcclient.InventoryClient, c model.CompanionResource, primary model.ResourceRef) (string, error) {
+	ctx, cancel := grpcclient.Context()
+	defer cancel()
+
+	switch c.BaseType {
+	case "LogicalResource":
+		resp, err := client.Logical.CreateLogicalResource(ctx, &logicalpb.LogicalResource{
+			Description: c.Type,
+			Name:        c.Value,
+			Type:        c.Type,
+			BaseType:    c.BaseType,
+			Value:       c.Value,
+			ResourceRelationship: []*logicalpb.LogicalResource_ResourceRelationship{{
+				RelationshipType: "reliesOn",
+				Resource: &logicalpb.LogicalResource_ResourceRelationship_Resource{
+					Id: primary.ID, Type: primary.Type, BaseType: primary.BaseType,
+				},
+			}},
+		})
+		return resp.GetId(), err
+
+	case "PhysicalResource":
+		resp, err := client.Physical.CreatePhysicalResource(ctx, &physicalpb.PhysicalResource{
+			Description: c.Type,
+			Name:        c.Value,
+			Type:        c.Type,
+			BaseType:    c.BaseType,
+			Value:       c.Value,
+			ResourceRelationship: []*physicalpb.PhysicalResource_ResourceRelationship{{
+				RelationshipType: "reliesOn",
+				Resource: &physicalpb.PhysicalResource_ResourceRelationship_Resource{
+					Id: primary.ID, Type: primary.Type, BaseType: primary.BaseType,
+				},
+			}},
+		})
+		return resp.GetId(), err
+
+	default:
+		return "", invalidf("UNSUPPORTED_BASE_TYPE", "unsupported companion baseType: %s", c.BaseType)
+	}
+}
+
+// itemValue is the inventory value a create item produces (its MSISDN characteristic)
+func itemValue(item model.BulkItem) string {
+	for _, rc := range item.ResourceCharacteristic {
+		if rc.Code == "MSISDN" {
+			return rc.Value
+		}
+	}
+	return item.Value
 }
 
 /*
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+)
+
+// InventoryReserver loads inventory documents and reserves them for a party
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryReserver interface {
+	FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error)
+	Reserve(ctx context.Context, id, from string, res model.Reservation) error
+}
+
+// ReservationProcessor moves resources to Reserved for one party until expiresAt.
+// Expired reservations are released by the ReservationSweeper
+type ReservationProcessor struct {
+	itemRepo  BulkStatusItemRepo
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+	lifecycle *lifecycle.StateMachine
+
+	logical  InventoryReserver
+	physical InventoryReserver
+
+	party     model.RelatedParty
+	expiresAt time.Time
+}
+
+func NewReservationProcessor(
+	itemRepo BulkStatusItemRepo,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	sm *lifecycle.StateMachine,
+	logical InventoryReserver,
+	physical InventoryReserver,
+	party model.RelatedParty,
+	expiresAt time.Time,
+) *ReservationProcessor {
+	return &ReservationProcessor{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		lifecycle: sm,
+		logical:   logical,
+		physical:  physical,
+		party:     party,
+		expiresAt: expiresAt,
+	}
+}
+
+func (p *ReservationProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+) {
+	start := time.Now()
+	log.Printf("BULK RESERVE PROCESSOR STARTED: items=%d party=%s expiresAt=%s\n",
+		len(items), p.party.Name, p.expiresAt.Format(time.RFC3339))
+
+	reservation := model.Reservation{
+		Party:         p.party,
+		ExpiresAt:     p.expiresAt,
+		BulkRequestID: req.ID.Hex(),
+	}
+
+	counts := runPool(ctx, "reserve", items, p.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		return p.reserve(ctx, item, reservation)
+	})
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK RESERVE PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
+		len(items), success, failure, time.Since(start))
+}
+
+func (p *ReservationProcessor) reserve(ctx context.Context, item model.BulkItem, reservation model.Reservation) (string, error) {
+	var reserver InventoryReserver
+	switch item.BaseType {
+	case "LogicalResource":
+		reserver = p.logical
+	case "PhysicalResource":
+		reserver = p.physical
+	default:
+		return "failure", invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", item.BaseType)
+	}
+
+	res, err := reserver.FindResource(ctx, item.Type, item.Value)
+	if err != nil {
+		return "failure", err
+	}
+
+	if err := p.itemRepo.UpdateItemTransition(ctx, item.ID.Hex(), res.ResourceStatus, lifecycle.StatusReserved); err != nil {
+		log.Printf("[reserve] failed to record transition item=%s err=%v", item.ID.Hex(), err)
+	}
+
+	// only Available resources are reserved, even where the lifecycle would allow
+	// another status to move to Reserved
+	if res.ResourceStatus != lifecycle.StatusAvailable {
+		return "failure", fmt.Errorf("%w: only %s resources can be reserved, %s is %s",
+			lifecycle.ErrIllegalTransition, lifecycle.StatusAvailable, item.Value, res.ResourceStatus)
+	}
+	if err := p.lifecycle.Validate(res.ResourceStatus, lifecycle.StatusReserved); err != nil {
+		return "failure", err
+	}
+
+	if err := reserver.Reserve(ctx, res.ID.Hex(), lifecycle.StatusAvailable, reservation); err != nil {
+		return "failure", err
+	}
+	return "success", nil
+}
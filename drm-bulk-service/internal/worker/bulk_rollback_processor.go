@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+)
+
+// InventoryRestorer writes before-images back onto inventory documents
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryRestorer interface {
+	Restore(
+		ctx context.Context,
+		resourceType, value string,
+		before map[string]interface{},
+		missing []string,
+		appliedAt time.Time,
+	) error
+}
+
+// RollbackProcessor undoes a completed bulk update from its bulk_item_snapshots.
+// Items modified again after the original job are skipped, not overwritten
+type RollbackProcessor struct {
+	itemRepo  BulkItemUpdater
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+
+	logical  InventoryRestorer
+	physical InventoryRestorer
+}
+
+func NewRollbackProcessor(
+	itemRepo BulkItemUpdater,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	logical InventoryRestorer,
+	physical InventoryRestorer,
+) *RollbackProcessor {
+	return &RollbackProcessor{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		logical:   logical,
+		physical:  physical,
+	}
+}
+
+// Process restores every item; snapshots are keyed by the rollback BulkItem ID (hex)
+func (p *RollbackProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+	snapshots map[string]model.BulkItemSnapshot,
+) {
+	start := time.Now()
+	log.Printf("BULK ROLLBACK PROCESSOR STARTED: parent=%s items=%d\n", req.ParentRequestID, len(items))
+
+	counts := runPool(ctx, "rollback", items, p.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		snap, ok := snapshots[item.ID.Hex()]
+		if !ok || snap.AppliedAt == nil {
+			return "failure", newItemError("SNAPSHOT_NOT_FOUND", model.ErrorCategoryNotFound, "no applied snapshot for value=%s", item.Value)
+		}
+
+		var restorer InventoryRestorer
+		switch snap.BaseType {
+		case "LogicalResource":
+			restorer = p.logical
+		case "PhysicalResource":
+			restorer = p.physical
+		}
+		if restorer == nil {
+			return "failure", invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", snap.BaseType)
+		}
+
+		err := restorer.Restore(ctx, snap.Type, snap.Value, snap.Before, snap.Missing, *snap.AppliedAt)
+		switch {
+		case err == nil:
+			return "success", nil
+		case errors.Is(err, repository.ErrConflict):
+			return "skipped", fmt.Errorf("skipped: modified after the original bulk update: %w", err)
+		default:
+			return "failure", err
+		}
+	})
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK ROLLBACK PROCESSOR FINISHED: items=%d success=%d failure=%d skipped=%d duration=%s",
+		len(items), success, failure-counts["skipped"], counts["skipped"], time.Since(start))
+}
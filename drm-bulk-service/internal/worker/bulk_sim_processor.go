@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	grpcclient "drm-bulk-service/internal/grpc"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+
+	logicalpb "drm-bulk-service/internal/proto/logicalresource"
+	physicalpb "drm-bulk-service/internal/proto/physicalresource"
+)
+
+// Resource types created by SIM pairing on the logical side
+const (
+	simIMSIType   = "IMSI"
+	simMSISDNType = "MSISDN"
+)
+
+// InventoryResourceStore is the direct-Mongo access SIM pairing needs on either side
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryResourceStore interface {
+	InventoryRemover
+	AddRelationship(ctx context.Context, id string, rel model.ResourceRelationship) error
+}
+
+// SimBatchProcessor loads SIM batches: per row it creates the SIM card (ICCID,
+// PhysicalResource), its IMSI and MSISDN (LogicalResource) and links them.
+// A row is all-or-nothing (rowTx): on a partial failure the resources already
+// created are removed and the item is marked "rolled_back"
+type SimBatchProcessor struct {
+	invClient *grpcclient.InventoryClient
+	itemRepo  BulkItemUpdater
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+
+	logical  InventoryResourceStore
+	physical InventoryResourceStore
+
+	resourceStatus string // status of the created resources
+}
+
+func NewSimBatchProcessor(
+	inv *grpcclient.InventoryClient,
+	itemRepo BulkItemUpdater,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	logical InventoryResourceStore,
+	physical InventoryResourceStore,
+	resourceStatus string,
+) *SimBatchProcessor {
+	return &SimBatchProcessor{
+		invClient:      inv,
+		itemRepo:       itemRepo,
+		reqRepo:        reqRepo,
+		reportSvc:      reportSvc,
+		logical:        logical,
+		physical:       physical,
+		resourceStatus: resourceStatus,
+	}
+}
+
+func (p *SimBatchProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+) {
+	start := time.Now()
+	log.Printf("BULK SIM PROCESSOR STARTED: items=%d\n", len(items))
+
+	counts := runPool(ctx, "sim", items, p.itemRepo, p.pair)
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK SIM PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
+		len(items), success, failure, time.Since(start))
+}
+
+func (p *SimBatchProcessor) pair(ctx context.Context, item model.BulkItem) (string, error) {
+	chars := map[string]string{}
+	for _, rc := range item.ResourceCharacteristic {
+		chars[rc.Code] = rc.Value
+	}
+	iccid, imsi, msisdn := chars["ICCID"], chars["IMSI"], chars["MSISDN"]
+	if iccid == "" || imsi == "" || msisdn == "" {
+		return "failure", invalidf("MISSING_FIELD", "ICCID, IMSI and MSISDN are required")
+	}
+
+	tx := &rowTx{}
+
+	// ---------- 1. SIM card ----------
+	simID, err := p.createPhysical(ctx, item, iccid)
+	if err == nil {
+		simID, err = tx.RecordCreate(ctx, p.physical, item.Type, iccid, simID)
+	}
+	if err != nil {
+		return tx.Fail(ctx, fmt.Errorf("create SIM %s: %w", iccid, err))
+	}
+	sim := model.ResourceRef{ID: simID, Type: item.Type, BaseType: "PhysicalResource"}
+
+	// ---------- 2. IMSI (reliesOn SIM) ----------
+	imsiID, err := p.createLogical(ctx, simIMSIType, imsi, []model.ResourceRef{sim})
+	if err == nil {
+		imsiID, err = tx.RecordCreate(ctx, p.logical, simIMSIType, imsi, imsiID)
+	}
+	if err != nil {
+		return tx.Fail(ctx, fmt.Errorf("create IMSI %s: %w", imsi, err))
+	}
+	imsiRef := model.ResourceRef{ID: imsiID, Type: simIMSIType, BaseType: "LogicalResource"}
+
+	// ---------- 3. MSISDN (reliesOn SIM and IMSI) ----------
+	msisdnID, err := p.createLogical(ctx, simMSISDNType, msisdn, []model.ResourceRef{sim, imsiRef})
+	if err == nil {
+		msisdnID, err = tx.RecordCreate(ctx, p.logical, simMSISDNType, msisdn, msisdnID)
+	}
+	if err != nil {
+		return tx.Fail(ctx, fmt.Errorf("create MSISDN %s: %w", msisdn, err))
+	}
+	msisdnRef := model.ResourceRef{ID: msisdnID, Type: simMSISDNType, BaseType: "LogicalResource"}
+
+	// ---------- 4. SIM bundles its IMSI and MSISDN ----------
+	for _, ref := range []model.ResourceRef{imsiRef, msisdnRef} {
+		rel := model.ResourceRelationship{RelationshipType: "bundle", Resource: ref}
+		if err := p.physical.AddRelationship(ctx, simID, rel); err != nil {
+			return tx.Fail(ctx, fmt.Errorf("link SIM %s to %s: %w", iccid, ref.Type, err))
+		}
+	}
+
+	return "success", nil
+}
+
+// createPhysical creates the SIM card and returns the id Inventory reported (may be empty)
+func (p *SimBatchProcessor) createPhysical(ctx context.Context, item model.BulkItem, iccid string) (string, error) {
+	var chars []*physicalpb.PhysicalResource_ResourceCharacteristic
+	for _, rc := range item.ResourceCharacteristic {
+		if rc.Code == "IMSI" || rc.Code == "MSISDN" || rc.Value == "" {
+			continue
+		}
+		chars = append(chars, &physicalpb.PhysicalResource_ResourceCharacteristic{
+			Code:             rc.Code,
+			Name:             rc.Code,
+			Value:            rc.Value,
+			PublicIdentifier: !model.SecretCharacteristics[rc.Code],
+		})
+	}
+
+	callCtx, cancel := grpcclient.Context()
+	defer cancel()
+
+	resp, err := p.invClient.Physical.CreatePhysicalResource(callCtx, &physicalpb.PhysicalResource{
+		Description:            item.Type,
+		Name:                   iccid,
+		Type:                   item.Type,
+		BaseType:               "PhysicalResource",
+		Value:                  iccid,
+		ResourceStatus:         p.resourceStatus,
+		ResourceCharacteristic: chars,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetId(), nil
+}
+
+// createLogical creates an IMSI / MSISDN and returns the id Inventory reported (may be empty)
+func (p *SimBatchProcessor) createLogical(ctx context.Context, resourceType, value string, reliesOn []model.ResourceRef) (string, error) {
+	var rels []*logicalpb.LogicalResource_ResourceRelationship
+	for _, ref := range reliesOn {
+		rels = append(rels, &logicalpb.LogicalResource_ResourceRelationship{
+			RelationshipType: "reliesOn",
+			Resource: &logicalpb.LogicalResource_ResourceRelationship_Resource{
+				Id:       ref.ID,
+				Type:     ref.Type,
+				BaseType: ref.BaseType,
+			},
+		})
+	}
+
+	callCtx, cancel := grpcclient.Context()
+	defer cancel()
+
+	resp, err := p.invClient.Logical.CreateLogicalResource(callCtx, &logicalpb.LogicalResource{
+		Description:    resourceType,
+		Name:           value,
+		Type:           resourceType,
+		BaseType:       "LogicalResource",
+		Value:          value,
+		ResourceStatus: p.resourceStatus,
+		ResourceCharacteristic: []*logicalpb.LogicalResource_ResourceCharacteristic{
+			{Code: resourceType, Name: resourceType, Value: value, PublicIdentifier: true},
+		},
+		ResourceRelationship: rels,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetId(), nil
+}
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+)
+
+// InventoryStatusChanger reads and changes the resourceStatus of inventory documents
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryStatusChanger interface {
+	GetStatus(ctx context.Context, resourceType, value string) (string, error)
+	TransitionStatus(ctx context.Context, resourceType, value, from, to string) error
+}
+
+// TransitionRecorder stores the from/to status of a BulkItem
+type TransitionRecorder interface {
+	UpdateItemTransition(ctx context.Context, itemID, fromStatus, toStatus string) error
+}
+
+// BulkStatusItemRepo is what StatusProcessor needs from the bulk_items repository
+type BulkStatusItemRepo interface {
+	BulkItemUpdater
+	TransitionRecorder
+}
+
+// StatusProcessor applies validated resourceStatus transitions in bulk
+type StatusProcessor struct {
+	itemRepo  BulkStatusItemRepo
+	reqRepo   BulkRequestUpdater
+	reportSvc *report.Service
+	lifecycle *lifecycle.StateMachine
+
+	logical  InventoryStatusChanger
+	physical InventoryStatusChanger
+}
+
+func NewStatusProcessor(
+	itemRepo BulkStatusItemRepo,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	sm *lifecycle.StateMachine,
+	logical InventoryStatusChanger,
+	physical InventoryStatusChanger,
+) *StatusProcessor {
+	return &StatusProcessor{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		lifecycle: sm,
+		logical:   logical,
+		physical:  physical,
+	}
+}
+
+func (p *StatusProcessor) Process(
+	ctx context.Context,
+	req model.BulkRequest,
+	items []model.BulkItem,
+) {
+	start := time.Now()
+	log.Printf("BULK STATUS PROCESSOR STARTED: items=%d\n", len(items))
+
+	counts := runPool(ctx, "status", items, p.itemRepo, p.changeStatus)
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
+
+	log.Printf("BULK STATUS PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
+		len(items), success, failure, time.Since(start))
+}
+
+func (p *StatusProcessor) changeStatus(ctx context.Context, item model.BulkItem) (string, error) {
+	changer, err := statusChangerFor(item.BaseType, p.logical, p.physical)
+	if err != nil {
+		return "failure", err
+	}
+
+	from, err := changer.GetStatus(ctx, item.Type, item.Value)
+	if err != nil {
+		return "failure", err
+	}
+
+	if err := p.itemRepo.UpdateItemTransition(ctx, item.ID.Hex(), from, item.ToStatus); err != nil {
+		log.Printf("[status] failed to record transition item=%s err=%v", item.ID.Hex(), err)
+	}
+
+	if err := p.lifecycle.Validate(from, item.ToStatus); err != nil {
+		return "failure", err
+	}
+
+	if err := changer.TransitionStatus(ctx, item.Type, item.Value, from, item.ToStatus); err != nil {
+		return "failure", err
+	}
+	return "success", nil
+}
+
+// statusChangerFor picks the inventory collection for a baseType
+func statusChangerFor(baseType string, logical, physical InventoryStatusChanger) (InventoryStatusChanger, error) {
+	switch baseType {
+	case "LogicalResource":
+		if logical == nil {
+			return nil, fmt.Errorf("no logical inventory status changer configured")
+		}
+		return logical, nil
+	case "PhysicalResource":
+		if physical == nil {
+			return nil, fmt.Errorf("no physical inventory status changer configured")
+		}
+		return physical, nil
+	default:
+		return nil, invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", baseType)
+	}
+}
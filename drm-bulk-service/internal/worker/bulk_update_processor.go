@@ -2,40 +2,56 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	grpcclient "drm-bulk-service/internal/grpc"
 	"drm-bulk-service/internal/model"
 	"drm-bulk-service/internal/report"
+	"drm-bulk-service/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // InventoryUpdater abstracts update of inventory collections
 // Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository, etc
 type InventoryUpdater interface {
-	UpdateByTypeAndValue(ctx context.Context, resourceType, value string, fields map[string]string) error
+	UpdateByTypeAndValue(ctx context.Context, resourceType, value string, fields, expected map[string]string) (model.InventoryUpdateResult, error)
+}
+
+// SnapshotStore persists before-images of updated inventory fields (bulk_item_snapshots)
+type SnapshotStore interface {
+	Insert(ctx context.Context, snap *model.BulkItemSnapshot) error
+}
+
+// BulkUpdateItemRepo records item outcomes and the before/after values of a bulk update
+// Concrete implementation: BulkItemRepository
+type BulkUpdateItemRepo interface {
+	BulkItemUpdater
+	UpdateItemChanges(ctx context.Context, itemID string, changes map[string]model.FieldChange) error
 }
 
 type UpdateProcessor struct {
 	invClient *grpcclient.InventoryClient
-	itemRepo  BulkItemUpdater
+	itemRepo  BulkUpdateItemRepo
 	reqRepo   BulkRequestUpdater
 	reportSvc *report.Service
 
 	logicalUpdater  InventoryUpdater
 	physicalUpdater InventoryUpdater
+	snapshots       SnapshotStore
 }
 
 func NewUpdateProcessor(
 	inv *grpcclient.InventoryClient,
-	itemRepo BulkItemUpdater,
+	itemRepo BulkUpdateItemRepo,
 	reqRepo BulkRequestUpdater,
 	reportSvc *report.Service,
 	logicalUpdater InventoryUpdater,
 	physicalUpdater InventoryUpdater,
+	snapshots SnapshotStore,
 ) *UpdateProcessor {
 	return &UpdateProcessor{
 		invClient:       inv,
@@ -44,6 +60,7 @@ func NewUpdateProcessor(
 		reportSvc:       reportSvc,
 		logicalUpdater:  logicalUpdater,
 		physicalUpdater: physicalUpdater,
+		snapshots:       snapshots,
 	}
 }
 
@@ -55,95 +72,101 @@ func (p *UpdateProcessor) Process(
 	start := time.Now()
 	log.Printf("BULK UPDATE PROCESSOR STARTED: items=%d\n", len(items))
 
-	const workerCount = 10
-
-	jobs := make(chan model.BulkItem)
-	var wg sync.WaitGroup
-	var successCount int32
-	var failureCount int32
-
-	// workers
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for item := range jobs {
-				status := "success"
-				errMsg := ""
-
-				if err := p.updateInventoryItem(ctx, item); err != nil {
-					status = "failure"
-					errMsg = err.Error()
-					log.Printf("[update worker %d] update failed item=%s err=%v",
-						workerID, item.ID.Hex(), err)
-				}
-
-				if err := p.itemRepo.UpdateItemStatusWithError(
-					ctx,
-					item.ID.Hex(),
-					status,
-					errMsg,
-				); err != nil {
-					log.Printf("[update worker %d] mongo update failed item=%s err=%v",
-						workerID, item.ID.Hex(), err)
-				}
-
-				if status == "success" {
-					atomic.AddInt32(&successCount, 1)
-				} else {
-					atomic.AddInt32(&failureCount, 1)
-				}
-			}
-		}(i + 1)
-	}
-
-	// send jobs
-	go func() {
-		defer close(jobs)
-		for _, item := range items {
-			select {
-			case <-ctx.Done():
-				log.Println("bulk update context cancelled")
-				return
-			case jobs <- item:
-			}
+	counts := runPool(ctx, "update", items, p.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		modified, err := p.updateInventoryItem(ctx, item)
+		switch {
+		case err == nil && !modified:
+			// matched, but every field already had the new value
+			return "unchanged", nil
+		case err == nil:
+			return "success", nil
+		case errors.Is(err, repository.ErrConflict):
+			// document changed since the expected.* values were taken
+			return "conflict", err
+		default:
+			return "failure", err
 		}
-	}()
-
-	wg.Wait()
-
-	success := int(atomic.LoadInt32(&successCount))
-	failure := int(atomic.LoadInt32(&failureCount))
-	processed := success + failure
+	})
+	success, failure := completeRequest(ctx, p.reqRepo, p.reportSvc, req, counts)
 
-	_ = p.reqRepo.UpdateCounts(ctx, req.ID.Hex(), processed, success, failure)
-	_ = p.reqRepo.UpdateStatus(ctx, req.ID.Hex(), "completed")
-	_ = p.reportSvc.Finalize(ctx, req)
-
-	log.Printf("BULK UPDATE PROCESSOR FINISHED: items=%d success=%d failure=%d duration=%s",
-		len(items), success, failure, time.Since(start))
+	log.Printf("BULK UPDATE PROCESSOR FINISHED: items=%d success=%d unchanged=%d failure=%d conflict=%d duration=%s",
+		len(items), success-counts["unchanged"], counts["unchanged"], failure-counts["conflict"], counts["conflict"], time.Since(start))
 }
 
-func (p *UpdateProcessor) updateInventoryItem(ctx context.Context, item model.BulkItem) error {
+// updateInventoryItem applies one update row and records its before/after values.
+// It reports whether the inventory document was modified
+func (p *UpdateProcessor) updateInventoryItem(ctx context.Context, item model.BulkItem) (bool, error) {
 	fields := item.UpdateFields
 	if fields == nil {
 		fields = map[string]string{}
 	}
 
+	var updater InventoryUpdater
 	switch item.BaseType {
 	case "LogicalResource":
 		if p.logicalUpdater == nil {
-			return fmt.Errorf("no logical inventory updater configured")
+			return false, fmt.Errorf("no logical inventory updater configured")
 		}
-		return p.logicalUpdater.UpdateByTypeAndValue(ctx, item.Type, item.Value, fields)
+		updater = p.logicalUpdater
 
 	case "PhysicalResource":
 		if p.physicalUpdater == nil {
-			return fmt.Errorf("no physical inventory updater configured")
+			return false, fmt.Errorf("no physical inventory updater configured")
 		}
-		return p.physicalUpdater.UpdateByTypeAndValue(ctx, item.Type, item.Value, fields)
+		updater = p.physicalUpdater
 
 	default:
-		return fmt.Errorf("unsupported baseType: %s", item.BaseType)
+		return false, invalidf("UNSUPPORTED_BASE_TYPE", "unsupported baseType: %s", item.BaseType)
+	}
+
+	// The before-image comes back from the update itself (findOneAndUpdate)
+	res, err := updater.UpdateByTypeAndValue(ctx, item.Type, item.Value, fields, item.Expected)
+	if err != nil {
+		return false, err
+	}
+
+	// ---------- Before-image (rollback) ----------
+	// An unchanged document needs no rollback, so it gets no snapshot
+	if p.snapshots != nil && res.Modified > 0 {
+		appliedAt := res.UpdatedAt
+		snap := &model.BulkItemSnapshot{
+			BulkRequestID: item.BulkRequestID,
+			BulkItemID:    item.ID,
+			Value:         item.Value,
+			Type:          item.Type,
+			BaseType:      item.BaseType,
+			Before:        res.Before,
+			Missing:       res.Missing,
+			AppliedAt:     &appliedAt,
+		}
+		if err := p.snapshots.Insert(ctx, snap); err != nil {
+			return true, fmt.Errorf("failed to store snapshot: %w", err)
+		}
+	}
+
+	if len(fields) > 0 {
+		changes := make(map[string]model.FieldChange, len(fields))
+		for f, after := range fields {
+			changes[f] = model.FieldChange{Before: fieldString(res.Before[f]), After: after}
+		}
+		if err := p.itemRepo.UpdateItemChanges(ctx, item.ID.Hex(), changes); err != nil {
+			log.Printf("[update] failed to record changes item=%s err=%v", item.ID.Hex(), err)
+		}
+	}
+	return res.Modified > 0, nil
+}
+
+// fieldString renders a before-image value the way it would be written in an update CSV
+func fieldString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case primitive.DateTime:
+		return t.Time().UTC().Format(time.RFC3339Nano)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
 	}
+	return fmt.Sprint(v)
 }
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// itemError is a processor error that carries its own code and category
+type itemError struct {
+	code     string
+	category string
+	err      error
+}
+
+func (e *itemError) Error() string { return e.err.Error() }
+func (e *itemError) Unwrap() error { return e.err }
+
+func newItemError(code, category, format string, args ...any) error {
+	return &itemError{code: code, category: category, err: fmt.Errorf(format, args...)}
+}
+
+// invalidf reports a row the processor cannot execute as given
+func invalidf(code, format string, args ...any) error {
+	return newItemError(code, model.ErrorCategoryValidation, format, args...)
+}
+
+// classifyError maps an item error to a stable code and category: processor
+// errors, repository sentinels, lifecycle violations, Mongo and gRPC failures
+func classifyError(err error) (code, category string) {
+	if err == nil {
+		return "", ""
+	}
+
+	var ie *itemError
+	if errors.As(err, &ie) {
+		return ie.code, ie.category
+	}
+
+	switch {
+	case errors.Is(err, lifecycle.ErrIllegalTransition):
+		return "ILLEGAL_TRANSITION", model.ErrorCategoryValidation
+	case errors.Is(err, repository.ErrResourceNotFound):
+		return "NOT_FOUND", model.ErrorCategoryNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return "CONFLICT", model.ErrorCategoryConflict
+	case errors.Is(err, repository.ErrInvalidValue):
+		return "INVALID_VALUE", model.ErrorCategoryValidation
+	case errors.Is(err, repository.ErrStatusChanged):
+		return "STATUS_CHANGED", model.ErrorCategoryConflict
+	case errors.Is(err, repository.ErrRelationshipExists):
+		return "RELATIONSHIP_EXISTS", model.ErrorCategoryDuplicate
+	case mongo.IsDuplicateKeyError(err):
+		return "DUPLICATE_KEY", model.ErrorCategoryDuplicate
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return "TIMEOUT", model.ErrorCategoryInventoryUnavailable
+	case mongo.IsNetworkError(err):
+		return "NETWORK_ERROR", model.ErrorCategoryInventoryUnavailable
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return grpcCode(st.Code())
+	}
+	return "INTERNAL", model.ErrorCategoryInternal
+}
+
+// grpcCode maps an Inventory gRPC status to a code and category
+func grpcCode(c codes.Code) (string, string) {
+	switch c {
+	case codes.AlreadyExists:
+		return "ALREADY_EXISTS", model.ErrorCategoryDuplicate
+	case codes.NotFound:
+		return "NOT_FOUND", model.ErrorCategoryNotFound
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return upperSnake(c.String()), model.ErrorCategoryValidation
+	case codes.Aborted:
+		return "ABORTED", model.ErrorCategoryConflict
+	case codes.DeadlineExceeded:
+		return "TIMEOUT", model.ErrorCategoryInventoryUnavailable
+	case codes.Unavailable, codes.ResourceExhausted, codes.Canceled:
+		return upperSnake(c.String()), model.ErrorCategoryInventoryUnavailable
+	default:
+		return upperSnake(c.String()), model.ErrorCategoryInternal
+	}
+}
+
+// upperSnake turns a gRPC code name such as AlreadyExists into ALREADY_EXISTS
+func upperSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/export"
+	"drm-bulk-service/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportJobUpdater records the progress and outcome of an export job
+// Concrete implementation: ExportJobRepository
+type ExportJobUpdater interface {
+	UpdateStatus(ctx context.Context, id string, status string) error
+	Complete(ctx context.Context, id string, fileID primitive.ObjectID, fileName string, rows int) error
+	Fail(ctx context.Context, id string, errMsg string) error
+}
+
+// ExportProcessor runs an export job in the background and stores its file in GridFS,
+// independent of the HTTP connection that created it
+type ExportProcessor struct {
+	jobRepo   ExportJobUpdater
+	exportSvc *export.Service
+}
+
+func NewExportProcessor(jobRepo ExportJobUpdater, exportSvc *export.Service) *ExportProcessor {
+	return &ExportProcessor{
+		jobRepo:   jobRepo,
+		exportSvc: exportSvc,
+	}
+}
+
+func (p *ExportProcessor) Process(ctx context.Context, job model.ExportJob) {
+	start := time.Now()
+	log.Printf("EXPORT PROCESSOR STARTED: job=%s baseType=%s", job.ID.Hex(), job.Query.BaseType)
+
+	_ = p.jobRepo.UpdateStatus(ctx, job.ID.Hex(), "processing")
+
+	name := export.FileName(job.ID.Hex())
+	fileID, rows, err := p.exportSvc.Store(ctx, name, job.Query)
+	if err != nil {
+		log.Printf("[export] job=%s failed after %d rows: %v", job.ID.Hex(), rows, err)
+		_ = p.jobRepo.Fail(ctx, job.ID.Hex(), err.Error())
+		return
+	}
+
+	if err := p.jobRepo.Complete(ctx, job.ID.Hex(), fileID, name, rows); err != nil {
+		log.Printf("[export] job=%s failed to record file %s: %v", job.ID.Hex(), fileID.Hex(), err)
+		return
+	}
+
+	log.Printf("EXPORT PROCESSOR FINISHED: job=%s rows=%d duration=%s", job.ID.Hex(), rows, time.Since(start))
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+)
+
+const workerCount = 10
+
+// itemFunc executes one bulk item and returns its final item status
+// ("success", "failure", ...) together with the error to record, if any
+type itemFunc func(ctx context.Context, item model.BulkItem) (string, error)
+
+// runPool fans items out to workerCount goroutines, stores every outcome on the
+// BulkItem and returns the number of items per final status.
+// Items that were already rejected at ingest (status "failure") are counted but not executed
+func runPool(
+	ctx context.Context,
+	name string,
+	items []model.BulkItem,
+	itemRepo BulkItemUpdater,
+	fn itemFunc,
+) map[string]int {
+	jobs := make(chan model.BulkItem)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counts := map[string]int{}
+
+	pending := make([]model.BulkItem, 0, len(items))
+	for _, item := range items {
+		if item.Status == "failure" {
+			counts[item.Status]++
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for item := range jobs {
+				status, err := fn(ctx, item)
+				errCode, errCategory := classifyError(err)
+				errMsg := ""
+				if err != nil {
+					errMsg = err.Error()
+					log.Printf("[%s worker %d] item=%s status=%s err=%v",
+						name, workerID, item.ID.Hex(), status, err)
+				}
+
+				if err := itemRepo.UpdateItemResult(
+					ctx,
+					item.ID.Hex(),
+					status,
+					errMsg,
+					errCode,
+					errCategory,
+				); err != nil {
+					log.Printf("[%s worker %d] mongo update failed item=%s err=%v",
+						name, workerID, item.ID.Hex(), err)
+				}
+
+				mu.Lock()
+				counts[status]++
+				mu.Unlock()
+			}
+		}(i + 1)
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, item := range pending {
+			select {
+			case <-ctx.Done():
+				log.Printf("%s context cancelled, stopping", name)
+				return
+			case jobs <- item:
+			}
+		}
+	}()
+
+	wg.Wait()
+	return counts
+}
+
+// completeRequest stores the final counts, marks the BulkRequest completed and builds the report.
+// Every status other than "success" and "unchanged" counts as a failure
+func completeRequest(
+	ctx context.Context,
+	reqRepo BulkRequestUpdater,
+	reportSvc *report.Service,
+	req model.BulkRequest,
+	counts map[string]int,
+) (success, failure int) {
+	for status, n := range counts {
+		if model.ItemSucceeded(status) {
+			success += n
+		} else {
+			failure += n
+		}
+	}
+
+	_ = reqRepo.UpdateCounts(ctx, req.ID.Hex(), success+failure, success, failure)
+	_ = reqRepo.UpdateStatusCounts(ctx, req.ID.Hex(), counts)
+	_ = reqRepo.UpdateStatus(ctx, req.ID.Hex(), "completed")
+	_ = reportSvc.Finalize(ctx, req)
+	return success, failure
+}
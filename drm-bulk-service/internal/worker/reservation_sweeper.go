@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"drm-bulk-service/internal/lifecycle"
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+)
+
+// sweepBatchSize caps the resources released by one system BulkRequest
+const sweepBatchSize = 1000
+
+// ReservationReleaser finds expired reservations and returns the resources to Available
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type ReservationReleaser interface {
+	FindExpiredReservations(ctx context.Context, now time.Time, limit int64) ([]model.InventoryResource, error)
+	ReleaseReservation(ctx context.Context, res model.InventoryResource, now time.Time) error
+}
+
+// BulkRequestCreator is what the sweeper needs to record its own BulkRequests
+type BulkRequestCreator interface {
+	BulkRequestUpdater
+	Insert(ctx context.Context, req *model.BulkRequest) error
+	UpdateTotalCount(ctx context.Context, reqID string, total int) error
+}
+
+// BulkItemCreator is what the sweeper needs to record its own BulkItems
+type BulkItemCreator interface {
+	BulkStatusItemRepo
+	InsertMany(ctx context.Context, items []model.BulkItem) error
+}
+
+// ReservationSweeper periodically releases expired reservations back to Available.
+// Every sweep that finds expired resources is recorded as a system-generated
+// BulkRequest (operation "release") with one item per resource and a report
+type ReservationSweeper struct {
+	itemRepo  BulkItemCreator
+	reqRepo   BulkRequestCreator
+	reportSvc *report.Service
+	lifecycle *lifecycle.StateMachine
+
+	logical  ReservationReleaser
+	physical ReservationReleaser
+}
+
+func NewReservationSweeper(
+	itemRepo BulkItemCreator,
+	reqRepo BulkRequestCreator,
+	reportSvc *report.Service,
+	sm *lifecycle.StateMachine,
+	logical ReservationReleaser,
+	physical ReservationReleaser,
+) *ReservationSweeper {
+	return &ReservationSweeper{
+		itemRepo:  itemRepo,
+		reqRepo:   reqRepo,
+		reportSvc: reportSvc,
+		lifecycle: sm,
+		logical:   logical,
+		physical:  physical,
+	}
+}
+
+// Run sweeps every interval until ctx is cancelled
+func (s *ReservationSweeper) Run(ctx context.Context, interval time.Duration) {
+	log.Printf("RESERVATION SWEEPER STARTED: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.SweepOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Printf("RESERVATION SWEEPER STOPPED")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepOnce releases the expired reservations of both inventory collections
+func (s *ReservationSweeper) SweepOnce(ctx context.Context) {
+	for _, side := range []struct {
+		baseType string
+		releaser ReservationReleaser
+	}{
+		{"LogicalResource", s.logical},
+		{"PhysicalResource", s.physical},
+	} {
+		if side.releaser == nil {
+			continue
+		}
+		if err := s.sweep(ctx, side.baseType, side.releaser); err != nil {
+			log.Printf("[sweeper] %s sweep failed: %v", side.baseType, err)
+		}
+	}
+}
+
+func (s *ReservationSweeper) sweep(ctx context.Context, baseType string, releaser ReservationReleaser) error {
+	now := time.Now()
+
+	expired, err := releaser.FindExpiredReservations(ctx, now, sweepBatchSize)
+	if err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	req := model.BulkRequest{
+		Operation:    "release",
+		BaseType:     baseType,
+		FileName:     "reservation-sweep-" + now.Format("20060102T150405"),
+		UserName:     "system",
+		UserRole:     "system",
+		UserType:     "system",
+		UserBaseType: "system",
+	}
+	if err := s.reqRepo.Insert(ctx, &req); err != nil {
+		return fmt.Errorf("create release request: %w", err)
+	}
+
+	items := make([]model.BulkItem, len(expired))
+	for i, res := range expired {
+		items[i] = model.BulkItem{
+			BulkRequestID: req.ID,
+			Value:         res.Value,
+			Type:          res.Type,
+			BaseType:      baseType,
+			Status:        "pending",
+			FromStatus:    lifecycle.StatusReserved,
+			ToStatus:      lifecycle.StatusAvailable,
+		}
+	}
+	if err := s.itemRepo.InsertMany(ctx, items); err != nil {
+		_ = s.reqRepo.UpdateStatus(ctx, req.ID.Hex(), "failed")
+		return fmt.Errorf("save release items: %w", err)
+	}
+	_ = s.reqRepo.UpdateTotalCount(ctx, req.ID.Hex(), len(items))
+
+	// InsertMany assigned the item ids; map them back to the resources to release
+	byItem := make(map[string]model.InventoryResource, len(items))
+	for i, item := range items {
+		byItem[item.ID.Hex()] = expired[i]
+	}
+
+	counts := runPool(ctx, "release", items, s.itemRepo, func(ctx context.Context, item model.BulkItem) (string, error) {
+		if err := s.lifecycle.Validate(lifecycle.StatusReserved, lifecycle.StatusAvailable); err != nil {
+			return "failure", err
+		}
+		if err := releaser.ReleaseReservation(ctx, byItem[item.ID.Hex()], now); err != nil {
+			return "failure", err
+		}
+		return "success", nil
+	})
+	success, failure := completeRequest(ctx, s.reqRepo, s.reportSvc, req, counts)
+
+	log.Printf("RESERVATION SWEEP %s: request=%s released=%d failed=%d",
+		baseType, req.ID.Hex(), success, failure)
+	return nil
+}
@@ -0,0 +1,279 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"drm-bulk-service/internal/model"
+	"drm-bulk-service/internal/report"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// purgeBatchSize caps the requests / report versions loaded per purge query
+const purgeBatchSize = 500
+
+// RetentionPolicy is how long job data is kept. A zero age keeps that data forever
+type RetentionPolicy struct {
+	ItemMaxAge   time.Duration // BulkItems (and update snapshots) of finished requests
+	ReportMaxAge time.Duration // report versions and their GridFS files
+	ExportMaxAge time.Duration // finished export jobs and their GridFS files
+}
+
+// ParseRetentionPolicy reads the ITEM_RETENTION_DAYS / REPORT_RETENTION_DAYS /
+// EXPORT_RETENTION_DAYS settings
+func ParseRetentionPolicy(itemDays, reportDays, exportDays string) (RetentionPolicy, error) {
+	items, err := strconv.Atoi(itemDays)
+	if err != nil || items < 0 {
+		return RetentionPolicy{}, fmt.Errorf("invalid item retention days %q", itemDays)
+	}
+	reports, err := strconv.Atoi(reportDays)
+	if err != nil || reports < 0 {
+		return RetentionPolicy{}, fmt.Errorf("invalid report retention days %q", reportDays)
+	}
+	exports, err := strconv.Atoi(exportDays)
+	if err != nil || exports < 0 {
+		return RetentionPolicy{}, fmt.Errorf("invalid export retention days %q", exportDays)
+	}
+	return RetentionPolicy{
+		ItemMaxAge:   time.Duration(items) * 24 * time.Hour,
+		ReportMaxAge: time.Duration(reports) * 24 * time.Hour,
+		ExportMaxAge: time.Duration(exports) * 24 * time.Hour,
+	}, nil
+}
+
+// RetentionRequestRepo finds requests whose items expired and records what was purged.
+// Concrete implementation: BulkRequestRepository
+type RetentionRequestRepo interface {
+	FindItemsExpired(ctx context.Context, before time.Time, limit int64) ([]model.BulkRequest, error)
+	MarkItemsPurged(ctx context.Context, id string, at time.Time) error
+	MarkReportsPurged(ctx context.Context, id string, at time.Time) error
+}
+
+// BulkItemPurger deletes per-item job data of a request.
+// Concrete implementations: BulkItemRepository, BulkItemSnapshotRepository
+type BulkItemPurger interface {
+	DeleteByBulkRequestID(ctx context.Context, bulkReqID string) (int64, error)
+}
+
+// ExpiredReportFinder lists report versions older than the retention cutoff.
+// Concrete implementation: BulkReportRepository
+type ExpiredReportFinder interface {
+	FindCreatedBefore(ctx context.Context, before time.Time, limit int64) ([]model.BulkReport, error)
+}
+
+// ExpiredExportStore lists and deletes finished export jobs.
+// Concrete implementation: ExportJobRepository
+type ExpiredExportStore interface {
+	FindFinishedBefore(ctx context.Context, before time.Time, limit int64) ([]model.ExportJob, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ExportFileDeleter removes the GridFS file of an export job.
+// Concrete implementation: export.Service
+type ExportFileDeleter interface {
+	DeleteFile(ctx context.Context, fileID primitive.ObjectID) error
+}
+
+// PurgeResult counts what one purge removed
+type PurgeResult struct {
+	Requests  int   `json:"requests"`  // requests whose items were purged
+	Items     int64 `json:"items"`     // BulkItems deleted
+	Snapshots int64 `json:"snapshots"` // update snapshots deleted
+	Reports   int   `json:"reports"`   // report versions deleted
+	Exports   int   `json:"exports"`   // export jobs deleted
+	Files     int   `json:"files"`     // GridFS files deleted
+}
+
+// RetentionJanitor deletes expired BulkItems, report versions and export jobs (with
+// their GridFS files). BulkRequest documents and their counts are kept and record when their
+// data was purged
+type RetentionJanitor struct {
+	policy    RetentionPolicy
+	reqRepo   RetentionRequestRepo
+	items     BulkItemPurger
+	snapshots BulkItemPurger
+	reports   ExpiredReportFinder
+	reportSvc *report.Service
+
+	exports     ExpiredExportStore
+	exportFiles ExportFileDeleter
+}
+
+func NewRetentionJanitor(
+	policy RetentionPolicy,
+	reqRepo RetentionRequestRepo,
+	items BulkItemPurger,
+	snapshots BulkItemPurger,
+	reports ExpiredReportFinder,
+	reportSvc *report.Service,
+	exports ExpiredExportStore,
+	exportFiles ExportFileDeleter,
+) *RetentionJanitor {
+	return &RetentionJanitor{
+		policy:    policy,
+		reqRepo:   reqRepo,
+		items:     items,
+		snapshots: snapshots,
+		reports:   reports,
+		reportSvc: reportSvc,
+
+		exports:     exports,
+		exportFiles: exportFiles,
+	}
+}
+
+// Run purges every interval until ctx is cancelled
+func (j *RetentionJanitor) Run(ctx context.Context, interval time.Duration) {
+	log.Printf("RETENTION JANITOR STARTED: interval=%s items=%s reports=%s exports=%s",
+		interval, j.policy.ItemMaxAge, j.policy.ReportMaxAge, j.policy.ExportMaxAge)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.PurgeOnce(ctx); err != nil {
+			log.Printf("[retention] purge failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("RETENTION JANITOR STOPPED")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce deletes everything older than the policy allows
+func (j *RetentionJanitor) PurgeOnce(ctx context.Context) (PurgeResult, error) {
+	var result PurgeResult
+	now := time.Now()
+
+	if j.policy.ReportMaxAge > 0 {
+		if err := j.purgeReports(ctx, now, &result); err != nil {
+			return result, err
+		}
+	}
+	if j.policy.ItemMaxAge > 0 {
+		if err := j.purgeItems(ctx, now, &result); err != nil {
+			return result, err
+		}
+	}
+	if j.policy.ExportMaxAge > 0 {
+		if err := j.purgeExports(ctx, now, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if result != (PurgeResult{}) {
+		log.Printf("[retention] purged requests=%d items=%d snapshots=%d reports=%d exports=%d files=%d",
+			result.Requests, result.Items, result.Snapshots, result.Reports, result.Exports, result.Files)
+	}
+	return result, nil
+}
+
+func (j *RetentionJanitor) purgeReports(ctx context.Context, now time.Time, result *PurgeResult) error {
+	cutoff := now.Add(-j.policy.ReportMaxAge)
+
+	for {
+		reports, err := j.reports.FindCreatedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return fmt.Errorf("find expired reports: %w", err)
+		}
+
+		failed := 0
+		for _, rep := range reports {
+			files, err := j.reportSvc.Delete(ctx, rep)
+			result.Files += files
+			if err != nil {
+				// keep the report document so the next run retries its files
+				log.Printf("[retention] report %s of request %s: %v", rep.ID.Hex(), rep.RequestID.Hex(), err)
+				failed++
+				continue
+			}
+			result.Reports++
+			_ = j.reqRepo.MarkReportsPurged(ctx, rep.RequestID.Hex(), now)
+		}
+
+		if failed > 0 || len(reports) < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+func (j *RetentionJanitor) purgeItems(ctx context.Context, now time.Time, result *PurgeResult) error {
+	cutoff := now.Add(-j.policy.ItemMaxAge)
+
+	for {
+		reqs, err := j.reqRepo.FindItemsExpired(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return fmt.Errorf("find expired requests: %w", err)
+		}
+
+		failed := 0
+		for _, req := range reqs {
+			items, err := j.items.DeleteByBulkRequestID(ctx, req.ID.Hex())
+			result.Items += items
+			if err != nil {
+				log.Printf("[retention] items of request %s: %v", req.ID.Hex(), err)
+				failed++
+				continue
+			}
+			snaps, err := j.snapshots.DeleteByBulkRequestID(ctx, req.ID.Hex())
+			result.Snapshots += snaps
+			if err != nil {
+				log.Printf("[retention] snapshots of request %s: %v", req.ID.Hex(), err)
+				failed++
+				continue
+			}
+			if err := j.reqRepo.MarkItemsPurged(ctx, req.ID.Hex(), now); err != nil {
+				log.Printf("[retention] mark request %s purged: %v", req.ID.Hex(), err)
+				failed++
+				continue
+			}
+			result.Requests++
+		}
+
+		if failed > 0 || len(reqs) < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+func (j *RetentionJanitor) purgeExports(ctx context.Context, now time.Time, result *PurgeResult) error {
+	cutoff := now.Add(-j.policy.ExportMaxAge)
+
+	for {
+		jobs, err := j.exports.FindFinishedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return fmt.Errorf("find expired exports: %w", err)
+		}
+
+		failed := 0
+		for _, job := range jobs {
+			if job.FileID != nil {
+				if err := j.exportFiles.DeleteFile(ctx, *job.FileID); err != nil {
+					// keep the job document so the next run retries its file
+					log.Printf("[retention] export %s: %v", job.ID.Hex(), err)
+					failed++
+					continue
+				}
+				result.Files++
+			}
+			if err := j.exports.Delete(ctx, job.ID); err != nil {
+				log.Printf("[retention] export %s: %v", job.ID.Hex(), err)
+				failed++
+				continue
+			}
+			result.Exports++
+		}
+
+		if failed > 0 || len(jobs) < purgeBatchSize {
+			return nil
+		}
+	}
+}
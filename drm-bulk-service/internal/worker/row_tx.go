@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"drm-bulk-service/internal/model"
+)
+
+// InventoryRemover removes resources a row created when the row has to be compensated
+// Concrete implementations: InventoryLogicalRepository, InventoryPhysicalRepository
+type InventoryRemover interface {
+	FindResource(ctx context.Context, resourceType, value string) (*model.InventoryResource, error)
+	DeleteByID(ctx context.Context, id string) error
+}
+
+/*
+===========================
+Row transaction
+===========================
+*/
+
+// rowTx is the unit of work of one CSV row. Every inventory change the row makes
+// is recorded together with the compensating action that undoes it, so a failure
+// in a later step does not leave orphans behind
+type rowTx struct {
+	steps []txStep
+}
+
+type txStep struct {
+	name       string
+	compensate func(ctx context.Context) error
+}
+
+// Record registers a completed step and how to undo it
+func (tx *rowTx) Record(name string, compensate func(ctx context.Context) error) {
+	tx.steps = append(tx.steps, txStep{name: name, compensate: compensate})
+}
+
+// RecordCreate registers a resource Inventory just created and returns its id
+// (see resolveCreatedID). The compensation is registered before the id is resolved,
+// so a resource whose id cannot be determined is still removed: compensation then
+// looks it up by type and value
+func (tx *rowTx) RecordCreate(ctx context.Context, remover InventoryRemover, resourceType, value, id string) (string, error) {
+	tx.Record("create "+resourceType+" "+value, func(ctx context.Context) error {
+		if id == "" {
+			res, err := remover.FindResource(ctx, resourceType, value)
+			if err != nil {
+				return err
+			}
+			id = res.ID.Hex()
+		}
+		return remover.DeleteByID(ctx, id)
+	})
+
+	resolved, err := resolveCreatedID(ctx, remover, id, resourceType, value)
+	if err != nil {
+		return "", err
+	}
+	id = resolved
+	return id, nil
+}
+
+// Rollback runs every compensation, newest first. All steps are attempted;
+// the steps that could not be undone are returned as one error
+func (tx *rowTx) Rollback(ctx context.Context) error {
+	var errs []error
+	for i := len(tx.steps) - 1; i >= 0; i-- {
+		step := tx.steps[i]
+		if err := step.compensate(ctx); err != nil {
+			log.Printf("[row tx] compensation of %q failed: %v", step.name, err)
+			errs = append(errs, fmt.Errorf("undo %s: %w", step.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Fail rolls the row back after cause and returns the item outcome:
+// "rolled_back" with the original error when compensation succeeded,
+// "failure" when nothing had to be undone or compensation was incomplete
+func (tx *rowTx) Fail(ctx context.Context, cause error) (string, error) {
+	if len(tx.steps) == 0 {
+		return "failure", cause
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		return "failure", fmt.Errorf("%w; rollback incomplete: %v", cause, err)
+	}
+	return "rolled_back", cause
+}
+
+// resolveCreatedID returns the id Inventory reported for a created resource,
+// looking the resource up when the gRPC response carries none
+func resolveCreatedID(ctx context.Context, finder InventoryRemover, id, resourceType, value string) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+	res, err := finder.FindResource(ctx, resourceType, value)
+	if err != nil {
+		return "", fmt.Errorf("created but not found: %w", err)
+	}
+	return res.ID.Hex(), nil
+}